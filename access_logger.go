@@ -1,17 +1,234 @@
 package logger
 
 import (
+	"context"
 	apachelog "github.com/lestrrat-go/apache-logformat"
+	"io"
 	"net/http"
 	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
 )
 
-func NewAccessLog(handler http.Handler) http.Handler {
-	combinedLog, _ := apachelog.New(`%h %l %{X-Correlation-Id}o %t "%r" %>s %b "%{Referer}i" "%{User-agent}i"`)
-	return combinedLog.Wrap(handler, os.Stderr)
+// accessLogFieldsKey is the context key of the access log fields added by handlers.
+type accessLogFieldsKey struct{}
+
+// accessLogFields are the access log fields added by handlers, possibly from several goroutines.
+type accessLogFields struct {
+	mu     sync.Mutex
+	fields []zap.Field
+}
+
+// AddAccessLogField adds a field to the entry NewAccessLogJSON writes when the request
+// completes, e.g. the tenant id. It is safe for concurrent use and a no-op when ctx doesn't
+// come from a request served through NewAccessLogJSON.
+func AddAccessLogField(ctx context.Context, key string, value interface{}) {
+	fields, ok := ctx.Value(accessLogFieldsKey{}).(*accessLogFields)
+	if !ok {
+		return
+	}
+
+	fields.mu.Lock()
+	defer fields.mu.Unlock()
+
+	fields.fields = append(fields.fields, zap.Any(key, value))
+}
+
+// get returns the added fields.
+func (f *accessLogFields) get() []zap.Field {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.fields
+}
+
+// accessLogFormat is the combined log format with the correlation id.
+const accessLogFormat = `%h %l %{X-Correlation-Id}o %t "%r" %>s %b "%{Referer}i" "%{User-agent}i"`
+
+type (
+	// AccessLogOption configures the access loggers.
+	AccessLogOption func(*accessLogConfig)
+
+	// accessLogConfig is the access logger configuration.
+	accessLogConfig struct {
+		skip      []func(*http.Request) bool
+		route     func(*http.Request) string
+		bodyLimit int
+	}
+)
+
+// WithSkipPaths doesn't log requests to the exact paths, e.g. "/healthz" and "/readyz" of probes.
+func WithSkipPaths(paths ...string) AccessLogOption {
+	var skipped = make(map[string]bool, len(paths))
+	for _, p := range paths {
+		skipped[p] = true
+	}
+
+	return WithSkip(func(r *http.Request) bool {
+		return skipped[r.URL.Path]
+	})
+}
+
+// WithSkip doesn't log requests for which skip returns true.
+func WithSkip(skip func(*http.Request) bool) AccessLogOption {
+	return func(c *accessLogConfig) {
+		c.skip = append(c.skip, skip)
+	}
+}
+
+// WithRoute adds the route field to NewAccessLogJSON entries, the pattern of the matched route
+// returned by route after the request is served, e.g. "/users/{id}", which aggregates better
+// than the concrete path. The field is omitted when route returns "". For chi:
+//
+//	logger.WithRoute(func(r *http.Request) string {
+//		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+//			return rctx.RoutePattern()
+//		}
+//		return ""
+//	})
+func WithRoute(route func(*http.Request) string) AccessLogOption {
+	return func(c *accessLogConfig) {
+		c.route = route
+	}
+}
+
+// WithBodies adds the request_body and response_body fields to NewAccessLogJSON entries,
+// the first limit bytes of the bodies read and written by the handler, for debugging APIs.
+// Longer bodies end with "...[truncated]" and binary ones are replaced by their size.
+// The redact keys of the logger apply to JSON and form bodies. Bodies aren't captured by default.
+func WithBodies(limit int) AccessLogOption {
+	return func(c *accessLogConfig) {
+		c.bodyLimit = limit
+	}
+}
+
+// newAccessLogConfig applies the options.
+func newAccessLogConfig(opts []AccessLogOption) *accessLogConfig {
+	var c = new(accessLogConfig)
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// wrap returns logged, bypassed by skipped requests which are served by handler directly.
+func (c *accessLogConfig) wrap(handler, logged http.Handler) http.Handler {
+	if len(c.skip) == 0 {
+		return logged
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		for _, skip := range c.skip {
+			if skip(r) {
+				handler.ServeHTTP(w, r)
+				return
+			}
+		}
+
+		logged.ServeHTTP(w, r)
+	})
+}
+
+// NewAccessLog wraps handler with apache combined access logging to stderr.
+func NewAccessLog(handler http.Handler, opts ...AccessLogOption) (http.Handler, error) {
+	return NewAccessLogWithFormat(handler, accessLogFormat, opts...)
+}
+
+// NewAccessLogWithFormat wraps handler with access logging to stderr in apache log format.
+// It returns an error when the format can't be parsed.
+func NewAccessLogWithFormat(handler http.Handler, format string, opts ...AccessLogOption) (http.Handler, error) {
+	return NewAccessLogTo(handler, format, os.Stderr, opts...)
+}
+
+// NewAccessLogTo wraps handler with access logging to out in apache log format.
+// It returns an error when the format can't be parsed.
+func NewAccessLogTo(handler http.Handler, format string, out io.Writer, opts ...AccessLogOption) (http.Handler, error) {
+	combinedLog, err := apachelog.New(format)
+	if err != nil {
+		return nil, err
+	}
+
+	return newAccessLogConfig(opts).wrap(handler, combinedLog.Wrap(handler, out)), nil
+}
+
+// NewAccessLogJSON wraps handler with structured access logging to log, or to the logger stashed
+// in the request context by ContextWithLogger when there is one, so requests land in Graylog
+// as fields of the application logger:
+// method, path, status, bytes, duration_ms, remote_addr, user_agent, referer, client_application_id,
+// route (see WithRoute) and the bodies (see WithBodies), followed by the fields the handler added with AddAccessLogField.
+// Entries logged to log get the request_id field of NewRequestID.
+func NewAccessLogJSON(handler http.Handler, log *zap.Logger, opts ...AccessLogOption) http.Handler {
+	var c = newAccessLogConfig(opts)
+
+	return c.wrap(handler, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			start  = time.Now()
+			rec    = NewResponseRecorder(w)
+			fields = new(accessLogFields)
+		)
+
+		var (
+			served            http.ResponseWriter = rec
+			reqBody, respBody *bodyCapture
+		)
+
+		if c.bodyLimit > 0 {
+			reqBody, respBody = newBodyCapture(c.bodyLimit), newBodyCapture(c.bodyLimit)
+			served = &captureRecorder{ResponseRecorder: rec, body: respBody}
+
+			if r.Body != nil && r.Body != http.NoBody {
+				r.Body = teeBody{Reader: io.TeeReader(r.Body, reqBody), Closer: r.Body}
+			}
+		}
+
+		handler.ServeHTTP(served, r.WithContext(context.WithValue(r.Context(), accessLogFieldsKey{}, fields)))
+
+		var standard = []zap.Field{
+			zap.String("method", r.Method),
+			zap.String("path", r.URL.Path),
+			zap.Int("status", rec.Status),
+			zap.Int64("bytes", rec.Size),
+			zap.Float64("duration_ms", float64(time.Since(start))/float64(time.Millisecond)),
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.String("user_agent", r.UserAgent()),
+			zap.String("referer", r.Referer()),
+			zap.String("client_application_id", r.Header.Get("X-Client-Application-Id")),
+		}
+
+		if c.route != nil {
+			if route := c.route(r); route != "" {
+				standard = append(standard, zap.String("route", route))
+			}
+		}
+
+		if reqBody != nil {
+			if f, ok := reqBody.field("request_body"); ok {
+				standard = append(standard, f)
+			}
+
+			if f, ok := respBody.field("response_body"); ok {
+				standard = append(standard, f)
+			}
+		}
+
+		entryLog, ok := loggerFromContext(r.Context())
+		if !ok {
+			entryLog = log
+			if id := RequestIDFromContext(r.Context()); id != "" {
+				standard = append(standard, zap.String("request_id", id))
+			}
+		}
+
+		withTrace(r.Context(), entryLog).Info(r.Method+" "+r.URL.Path, append(standard, fields.get()...)...)
+	}))
 }
 
-func NewAccessLogWithFormat(handler http.Handler, format string) http.Handler {
-	combinedLog, _ := apachelog.New(format)
-	return combinedLog.Wrap(handler, os.Stderr)
+// AccessLogMiddleware returns NewAccessLogJSON logging to log as a middleware, for routers such as chi.
+func AccessLogMiddleware(log *zap.Logger, opts ...AccessLogOption) func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return NewAccessLogJSON(handler, log, opts...)
+	}
 }
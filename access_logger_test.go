@@ -0,0 +1,307 @@
+package logger_test
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.cantor.systems/logger"
+)
+
+func TestNewAccessLog(t *testing.T) {
+	handler, err := logger.NewAccessLog(http.NotFoundHandler())
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if handler == nil {
+		t.Fatal("nil handler")
+	}
+}
+
+func TestNewAccessLogWithFormat(t *testing.T) {
+	if _, err := logger.NewAccessLogWithFormat(http.NotFoundHandler(), "%h %P"); err == nil {
+		t.Fatal("expected error for broken format")
+	}
+}
+
+func TestNewAccessLogTo(t *testing.T) {
+	var out bytes.Buffer
+
+	handler, err := logger.NewAccessLogTo(http.NotFoundHandler(), `"%r" %>s`, &out)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if line := out.String(); line != "\"GET /missing HTTP/1.1\" 404\n" {
+		t.Fatalf("unexpected access log line %q", line)
+	}
+}
+
+func TestNewAccessLogJSON(t *testing.T) {
+	log, logs := logger.NewObserver()
+
+	handler := logger.NewAccessLogJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte("created"))
+	}), log)
+
+	req := httptest.NewRequest(http.MethodPost, "/items?id=1", nil)
+	req.Header.Set("User-Agent", "test")
+	req.Header.Set("Referer", "http://example.com")
+	req.Header.Set("X-Client-Application-Id", "mobile")
+
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(logger.ContextWithLogger(req.Context(), log)))
+
+	if logs.Len() != 1 {
+		t.Fatalf("expected one access entry but got %d", logs.Len())
+	}
+
+	entry := logs.All()[0]
+	fields := entry.ContextMap()
+
+	for key, expected := range map[string]interface{}{
+		"method":                "POST",
+		"path":                  "/items",
+		"status":                int64(http.StatusCreated),
+		"bytes":                 int64(len("created")),
+		"remote_addr":           req.RemoteAddr,
+		"user_agent":            "test",
+		"referer":               "http://example.com",
+		"client_application_id": "mobile",
+	} {
+		if fields[key] != expected {
+			t.Errorf("%s: expected %v but got %v", key, expected, fields[key])
+		}
+	}
+
+	if _, ok := fields["duration_ms"].(float64); !ok || entry.Message != "POST /items" {
+		t.Fatalf("unexpected entry: %v", entry)
+	}
+}
+
+func TestNewAccessLogSkip(t *testing.T) {
+	var out bytes.Buffer
+
+	handler, err := logger.NewAccessLogTo(http.NotFoundHandler(), `"%r" %>s`, &out,
+		logger.WithSkipPaths("/healthz", "/readyz"),
+		logger.WithSkip(func(r *http.Request) bool { return r.Method == http.MethodOptions }),
+	)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	for _, req := range []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/healthz", nil),
+		httptest.NewRequest(http.MethodGet, "/readyz", nil),
+		httptest.NewRequest(http.MethodOptions, "/missing", nil),
+	} {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("%s is not served", req.URL.Path)
+		}
+	}
+
+	if out.Len() != 0 {
+		t.Fatalf("skipped requests are logged: %q", out.String())
+	}
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/healthz/deep", nil))
+
+	if line := out.String(); line != "\"GET /healthz/deep HTTP/1.1\" 404\n" {
+		t.Fatalf("unexpected access log line %q", line)
+	}
+}
+
+func TestNewAccessLogJSONLogger(t *testing.T) {
+	log, logs := logger.NewObserver()
+	stashed, stashedLogs := logger.NewObserver()
+
+	handler := logger.NewRequestID(logger.NewAccessLogJSON(http.NotFoundHandler(), log), func() string { return "id" })
+
+	// without a stashed logger the entry goes to log with the request id
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/missing", nil))
+
+	if entries := logs.All(); len(entries) != 1 || entries[0].ContextMap()["request_id"] != "id" {
+		t.Fatalf("unexpected entries %v", entries)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(logger.ContextWithLogger(req.Context(), stashed)))
+
+	if entries := stashedLogs.All(); logs.Len() != 1 || len(entries) != 1 || entries[0].ContextMap()["request_id"] != "id" {
+		t.Fatalf("entry must go to the stashed logger: %v", entries)
+	}
+}
+
+func TestNewAccessLogJSONSkip(t *testing.T) {
+	log, logs := logger.NewObserver()
+	handler := logger.NewAccessLogJSON(http.NotFoundHandler(), log, logger.WithSkipPaths("/healthz"))
+
+	req := httptest.NewRequest(http.MethodGet, "/healthz", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(logger.ContextWithLogger(req.Context(), log)))
+
+	if logs.Len() != 0 {
+		t.Fatalf("skipped request is logged: %v", logs.All())
+	}
+}
+
+func TestAddAccessLogField(t *testing.T) {
+	log, logs := logger.NewObserver()
+
+	handler := logger.NewAccessLogJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				logger.AddAccessLogField(r.Context(), "field"+strconv.Itoa(i), i)
+			}(i)
+		}
+		wg.Wait()
+	}), log)
+
+	req := httptest.NewRequest(http.MethodGet, "/items", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(logger.ContextWithLogger(req.Context(), log)))
+
+	fields := logs.All()[0].ContextMap()
+	for i := 0; i < 10; i++ {
+		if key := "field" + strconv.Itoa(i); fields[key] != int64(i) {
+			t.Errorf("%s: expected %d but got %v", key, i, fields[key])
+		}
+	}
+
+	if fields["path"] != "/items" {
+		t.Fatalf("standard fields are lost: %v", fields)
+	}
+}
+
+func TestAddAccessLogFieldNone(t *testing.T) {
+	log, logs := logger.NewObserver()
+	handler := logger.NewAccessLogJSON(http.NotFoundHandler(), log)
+
+	req := httptest.NewRequest(http.MethodGet, "/missing", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(logger.ContextWithLogger(req.Context(), log)))
+
+	if fields := logs.All()[0].Context; fields[len(fields)-1].Key != "client_application_id" {
+		t.Fatalf("expected only the standard fields but got %v", fields)
+	}
+
+	logger.AddAccessLogField(req.Context(), "ignored", true) // outside of the access log
+}
+
+// routeKey is the context key of the test router pattern, mutable like the chi route context.
+type routeKey struct{}
+
+func TestAccessLogMiddlewareRoute(t *testing.T) {
+	log, logs := logger.NewObserver()
+
+	route := func(r *http.Request) string {
+		if pattern, ok := r.Context().Value(routeKey{}).(*string); ok {
+			return *pattern
+		}
+
+		return ""
+	}
+
+	// the router matches the pattern after the middleware is entered, like chi mounted middleware
+	handler := logger.AccessLogMiddleware(log, logger.WithRoute(route))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if pattern, ok := r.Context().Value(routeKey{}).(*string); ok {
+			*pattern = "/users/{id}"
+		}
+	}))
+
+	for _, withRoute := range []bool{true, false} {
+		req := httptest.NewRequest(http.MethodGet, "/users/42", nil)
+
+		ctx := logger.ContextWithLogger(req.Context(), log)
+		if withRoute {
+			ctx = context.WithValue(ctx, routeKey{}, new(string))
+		}
+
+		handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(ctx))
+	}
+
+	entries := logs.All()
+	if len(entries) != 2 {
+		t.Fatalf("expected two entries but got %d", len(entries))
+	}
+
+	if fields := entries[0].ContextMap(); fields["route"] != "/users/{id}" || fields["path"] != "/users/42" {
+		t.Fatalf("unexpected routed fields %v", fields)
+	}
+
+	if fields := entries[1].ContextMap(); fields["route"] != nil || fields["path"] != "/users/42" {
+		t.Fatalf("unexpected fields without route %v", fields)
+	}
+}
+
+func TestNewAccessLogJSONBodies(t *testing.T) {
+	log, logs := logger.NewObserver()
+
+	var read []byte
+	handler := logger.NewAccessLogJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		read, _ = ioutil.ReadAll(r.Body)
+		_, _ = w.Write([]byte("0123456789"))
+	}), log, logger.WithBodies(4))
+
+	for _, body := range []string{"hello world", "\xff\x00\x01", ""} {
+		req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+		handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(logger.ContextWithLogger(req.Context(), log)))
+
+		if string(read) != body {
+			t.Fatalf("handler read %q instead of the body %q", read, body)
+		}
+	}
+
+	entries := logs.All()
+	if len(entries) != 3 {
+		t.Fatalf("expected three entries but got %d", len(entries))
+	}
+
+	for i, want := range []interface{}{"hell...[truncated]", "[binary 3 bytes]", nil} {
+		if fields := entries[i].ContextMap(); fields["request_body"] != want || fields["response_body"] != "0123...[truncated]" {
+			t.Fatalf("unexpected bodies of entry %d: %v", i, fields)
+		}
+	}
+}
+
+func TestNewAccessLogJSONBodiesRedacted(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+		RedactKeys:     []string{"password"},
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	handler := logger.NewAccessLogJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(w, r.Body)
+	}), log.Logger, logger.WithBodies(1024))
+
+	for _, body := range []string{`{"user":"jo","password":"secret"}`, "user=jo&password=secret"} {
+		req := httptest.NewRequest(http.MethodPost, "/login", strings.NewReader(body))
+		handler.ServeHTTP(httptest.NewRecorder(), req.WithContext(logger.ContextWithLogger(req.Context(), log.Logger)))
+	}
+
+	for _, want := range []string{`{"password":"***","user":"jo"}`, "password=%2A%2A%2A&user=jo"} {
+		if msg := <-messages; msg["request_body"] != want || msg["response_body"] != want {
+			t.Fatalf("expected redacted bodies %s but got %v", want, msg)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package logger
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// OverflowPolicy is what the async writer does with a message logged while its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowDropNewest drops the logged message, so the logging goroutine never blocks.
+	OverflowDropNewest OverflowPolicy = iota
+
+	// OverflowDropOldest drops the oldest queued message to make room for the logged one.
+	OverflowDropOldest
+
+	// OverflowBlock waits for room in the queue, slowing down the logging goroutines
+	// to the pace of Graylog. Close unblocks the waiting writes.
+	OverflowBlock
+)
+
+// asyncWriter queues messages and writes them from a background goroutine.
+// When the queue is full the message is handled by the overflow policy.
+type asyncWriter struct {
+	dropped uint64 // accessed atomically, first for 64-bit alignment
+	aborted int32  // accessed atomically, set by abort
+
+	out    io.WriteCloser
+	policy OverflowPolicy
+	queue  chan asyncMessage
+	done   chan struct{}
+
+	stopOnce sync.Once
+	stop     chan struct{} // closed by Close to unblock the writes waiting for room
+
+	mu     sync.RWMutex // guards closed, so nothing is queued after Close
+	closed bool
+
+	onDrop func(err error)
+}
+
+// asyncMessage is a queued message, or a sync request when synced is set.
+type asyncMessage struct {
+	buf    []byte
+	synced chan error
+}
+
+// newAsyncWriter starts the goroutine draining the queue to out.
+func newAsyncWriter(out io.WriteCloser, size int, policy OverflowPolicy) *asyncWriter {
+	var w = &asyncWriter{
+		out:    out,
+		policy: policy,
+		queue:  make(chan asyncMessage, size),
+		done:   make(chan struct{}),
+		stop:   make(chan struct{}),
+	}
+
+	go w.run()
+
+	return w
+}
+
+// run writes queued messages until the queue is closed.
+func (w *asyncWriter) run() {
+	defer close(w.done)
+
+	for msg := range w.queue {
+		if atomic.LoadInt32(&w.aborted) == 1 {
+			if msg.synced != nil {
+				msg.synced <- errWriterClosed
+			} else {
+				atomic.AddUint64(&w.dropped, 1)
+			}
+
+			continue
+		}
+
+		if msg.synced != nil {
+			msg.synced <- syncWriter(w.out)
+			continue
+		}
+
+		// there is nobody to report the error to, it's fire and forget
+		_, _ = w.out.Write(msg.buf)
+	}
+}
+
+// Write implements io.Writer.
+// The message is copied, because zap reuses buf once Write returns.
+func (w *asyncWriter) Write(buf []byte) (int, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return 0, errWriterClosed
+	}
+
+	var msg = make([]byte, len(buf))
+	copy(msg, buf)
+
+	switch w.policy {
+	case OverflowBlock:
+		select {
+		case w.queue <- asyncMessage{buf: msg}:
+		case <-w.stop:
+			return 0, errWriterClosed
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case w.queue <- asyncMessage{buf: msg}:
+				return len(buf), nil
+			default:
+			}
+
+			select {
+			case oldest := <-w.queue:
+				if oldest.synced != nil {
+					// the sync request can't wait for the evicted messages
+					oldest.synced <- errQueueFull
+					continue
+				}
+
+				w.drop()
+			default:
+			}
+		}
+	default:
+		select {
+		case w.queue <- asyncMessage{buf: msg}:
+		default:
+			w.drop()
+		}
+	}
+
+	return len(buf), nil
+}
+
+// drop counts and reports a message dropped because the queue is full.
+func (w *asyncWriter) drop() {
+	atomic.AddUint64(&w.dropped, 1)
+
+	if w.onDrop != nil {
+		w.onDrop(errQueueFull)
+	}
+}
+
+// Dropped returns how many messages were dropped because the queue was full or abort discarded them.
+func (w *asyncWriter) Dropped() uint64 {
+	return atomic.LoadUint64(&w.dropped)
+}
+
+// Sync waits for the messages queued before it to be written and syncs the underlying writer,
+// e.g. sending the pending batch.
+func (w *asyncWriter) Sync() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return errWriterClosed
+	}
+
+	// the request isn't dropped like messages, it waits for room in the queue
+	var synced = make(chan error, 1)
+	w.queue <- asyncMessage{synced: synced}
+
+	return <-synced
+}
+
+// syncWriter syncs w when it supports it.
+func syncWriter(w io.Writer) error {
+	if s, ok := w.(zapcore.WriteSyncer); ok {
+		return s.Sync()
+	}
+
+	return nil
+}
+
+// abort discards the queued messages instead of writing them, once the current write returns,
+// and unblocks the writes waiting for room. Close still has to be called.
+func (w *asyncWriter) abort() {
+	atomic.StoreInt32(&w.aborted, 1)
+	w.stopOnce.Do(func() { close(w.stop) })
+}
+
+// Close stops accepting messages, waits for the queued ones to be written
+// and closes the underlying writer.
+func (w *asyncWriter) Close() error {
+	// the blocked writes hold the read lock
+	w.stopOnce.Do(func() { close(w.stop) })
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return errWriterClosed
+	}
+
+	w.closed = true
+	close(w.queue)
+	w.mu.Unlock()
+
+	<-w.done
+
+	return w.out.Close()
+}
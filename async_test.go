@@ -0,0 +1,294 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// blockingWriter records messages, blocking writes until released.
+type blockingWriter struct {
+	release chan struct{}
+
+	mu       sync.Mutex
+	messages []string
+	closed   bool
+}
+
+func (w *blockingWriter) Write(buf []byte) (int, error) {
+	<-w.release
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.messages = append(w.messages, string(buf))
+
+	return len(buf), nil
+}
+
+func (w *blockingWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+
+	return nil
+}
+
+func TestAsyncWriter(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	w := newAsyncWriter(out, 2, OverflowDropNewest)
+
+	buf := bytes.NewBufferString("first")
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	// the message must be copied, zap reuses its buffers
+	buf.Reset()
+	buf.WriteString("reused")
+
+	// the first message may or may not be taken by the goroutine yet,
+	// so write until the queue overflows
+	for i := 0; w.Dropped() == 0; i++ {
+		if i > 3 {
+			t.Fatal("queue doesn't overflow")
+		}
+
+		if _, err := w.Write([]byte("next")); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+	}
+
+	close(out.release)
+
+	if err := w.Close(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if !out.closed {
+		t.Fatal("underlying writer is not closed")
+	}
+
+	if len(out.messages) == 0 || out.messages[0] != "first" {
+		t.Fatalf("unexpected messages %q", out.messages)
+	}
+
+	if _, err := w.Write([]byte("late")); err != errWriterClosed {
+		t.Fatal("expected closed error but got:", err)
+	}
+}
+
+func TestAsyncWriterSync(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	close(out.release)
+
+	w := newAsyncWriter(out, 16, OverflowDropNewest)
+
+	for i := 0; i < 10; i++ {
+		if _, err := w.Write([]byte("queued")); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+	}
+
+	if err := w.Sync(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	out.mu.Lock()
+	written := len(out.messages)
+	out.mu.Unlock()
+
+	if written != 10 {
+		t.Fatalf("expected 10 written messages but got %d", written)
+	}
+
+	_ = w.Close()
+
+	if err := w.Sync(); err != errWriterClosed {
+		t.Fatal("expected closed error but got:", err)
+	}
+}
+
+func TestAsyncWriterDropOldest(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	w := newAsyncWriter(out, 2, OverflowDropOldest)
+
+	var written = []string{"0", "1", "2", "3", "4", "5"}
+	for _, msg := range written {
+		if _, err := w.Write([]byte(msg)); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+	}
+
+	close(out.release)
+	_ = w.Close()
+
+	// the goroutine may have taken the first message before the queue filled up
+	n := len(out.messages)
+	if n < 2 || out.messages[n-2] != "4" || out.messages[n-1] != "5" {
+		t.Fatalf("unexpected messages %q", out.messages)
+	}
+
+	if n+int(w.Dropped()) != len(written) {
+		t.Fatalf("%d messages are written and %d dropped", n, w.Dropped())
+	}
+}
+
+func TestAsyncWriterBlock(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	w := newAsyncWriter(out, 2, OverflowBlock)
+
+	var blocked = make(chan error)
+	go func() {
+		for i := 0; i < 4; i++ {
+			if _, err := w.Write([]byte("queued")); err != nil {
+				blocked <- err
+				return
+			}
+		}
+
+		blocked <- nil
+	}()
+
+	select {
+	case err := <-blocked:
+		t.Fatal("write doesn't block on a full queue:", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(out.release)
+
+	if err := <-blocked; err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if err := w.Sync(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	out.mu.Lock()
+	written := len(out.messages)
+	out.mu.Unlock()
+
+	if written != 4 || w.Dropped() != 0 {
+		t.Fatalf("%d messages are written and %d dropped", written, w.Dropped())
+	}
+
+	_ = w.Close()
+}
+
+func TestAsyncWriterBlockClose(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	w := newAsyncWriter(out, 1, OverflowBlock)
+
+	var blocked = make(chan error)
+	go func() {
+		for {
+			if _, err := w.Write([]byte("queued")); err != nil {
+				blocked <- err
+				return
+			}
+		}
+	}()
+
+	var closed = make(chan error)
+	go func() {
+		closed <- w.Close()
+	}()
+
+	select {
+	case err := <-blocked:
+		if err != errWriterClosed {
+			t.Fatal("expected closed error but got:", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("blocked write isn't released by Close")
+	}
+
+	close(out.release)
+
+	if err := <-closed; err != nil {
+		t.Fatal("error occurred:", err)
+	}
+}
+
+func TestLoggerShutdown(t *testing.T) {
+	out := &blockingWriter{release: make(chan struct{})}
+	async := newAsyncWriter(out, 16, OverflowDropNewest)
+	log := &Logger{Logger: zap.NewNop(), writer: async, async: async}
+
+	if _, err := async.Write([]byte("wedged")); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := log.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatal("expected deadline error but got:", err)
+	}
+
+	close(out.release)
+
+	out = &blockingWriter{release: make(chan struct{})}
+	close(out.release)
+
+	async = newAsyncWriter(out, 16, OverflowDropNewest)
+	log = &Logger{Logger: zap.NewNop(), writer: async, async: async}
+
+	if _, err := async.Write([]byte("drained")); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if err := log.Shutdown(context.Background()); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if len(out.messages) != 1 || !out.closed {
+		t.Fatalf("unexpected messages %q, closed %t", out.messages, out.closed)
+	}
+}
+
+func TestLoggerShutdownBlockedWriter(t *testing.T) {
+	// nothing reads the pipe, the write of the first message blocks like a wedged collector
+	conn, peer := net.Pipe()
+	defer peer.Close()
+
+	gelf := &writer{
+		conn:            conn,
+		transport:       TransportTCP,
+		compressionType: CompressionNone,
+	}
+
+	async := newAsyncWriter(gelf, 16, OverflowDropNewest)
+	log := &Logger{Logger: zap.NewNop(), writer: async, async: async, gelf: gelf}
+
+	for _, msg := range []string{"wedged", "queued"} {
+		if _, err := async.Write([]byte(msg)); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if err := log.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Fatal("expected deadline error but got:", err)
+	}
+
+	select {
+	case <-async.done:
+	case <-time.After(time.Second):
+		t.Fatal("async writer still blocked after Shutdown")
+	}
+
+	if dropped := async.Dropped(); dropped != 1 {
+		t.Fatal("expected the queued message to be dropped but got:", dropped)
+	}
+}
@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"bytes"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// batchWriter buffers messages and sends them in batches, in order.
+type batchWriter struct {
+	gelf     *writer
+	size     int
+	interval time.Duration
+
+	sendMu sync.Mutex // serializes the batches, taken before mu is released
+
+	mu      sync.Mutex // guards the fields below
+	pending [][]byte
+	bytes   int
+	timer   *time.Timer
+	closed  bool
+}
+
+// newBatchWriter creates a writer sending batches of size bytes, or after interval, to gelf.
+func newBatchWriter(gelf *writer, size int, interval time.Duration) *batchWriter {
+	return &batchWriter{gelf: gelf, size: size, interval: interval}
+}
+
+// Write implements io.Writer, batch send errors are returned by the write filling the batch.
+// Messages longer than MaxMessageBytes are truncated or dropped before they are batched.
+func (b *batchWriter) Write(buf []byte) (int, error) {
+	var size = len(buf)
+
+	buf, err := b.gelf.limitSize(buf)
+	if err != nil {
+		return 0, err
+	}
+
+	b.mu.Lock()
+
+	if b.closed {
+		b.mu.Unlock()
+		return 0, errWriterClosed
+	}
+
+	// copy the buffer, zap reuses it
+	b.pending = append(b.pending, append([]byte(nil), buf...))
+	b.bytes += len(buf)
+
+	if b.bytes < b.size {
+		if b.timer == nil {
+			b.timer = time.AfterFunc(b.interval, b.flushPending)
+		}
+
+		b.mu.Unlock()
+
+		return size, nil
+	}
+
+	return size, b.flushLocked()
+}
+
+// Sync sends the pending batch.
+func (b *batchWriter) Sync() error {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return errWriterClosed
+	}
+
+	return b.flushLocked()
+}
+
+// Close sends the pending batch and closes the Graylog writer.
+func (b *batchWriter) Close() error {
+	b.mu.Lock()
+	b.closed = true
+
+	err := b.flushLocked()
+	if cErr := b.gelf.Close(); err == nil {
+		err = cErr
+	}
+
+	return err
+}
+
+// flushPending sends the pending batch when the interval is over.
+func (b *batchWriter) flushPending() {
+	b.mu.Lock()
+
+	// errors are counted by the writer stats
+	_ = b.flushLocked()
+}
+
+// flushLocked sends the pending batch, b.mu must be held and is released. The batch is sent
+// before the ones taken after it, a size flush can't overtake a running interval flush.
+func (b *batchWriter) flushLocked() error {
+	batch := b.take()
+
+	b.sendMu.Lock()
+	defer b.sendMu.Unlock()
+
+	b.mu.Unlock()
+
+	return b.gelf.writeBatch(batch)
+}
+
+// take returns the pending batch and resets it, b.mu must be held.
+func (b *batchWriter) take() [][]byte {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	batch := b.pending
+	b.pending, b.bytes = nil, 0
+
+	return batch
+}
+
+// writeBatch sends the messages in a single write, normalize rejects batching for UDP and Kafka.
+func (w *writer) writeBatch(batch [][]byte) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	// streams separate the messages with the frame terminator, added to the last one by send,
+	// the HTTP body is the newline-delimited messages
+	var payload bytes.Buffer
+	for i, buf := range batch {
+		if i > 0 && w.transport != TransportHTTP {
+			payload.WriteByte(0x00)
+		}
+
+		payload.Write(buf)
+
+		if w.transport == TransportHTTP && !bytes.HasSuffix(buf, []byte{'\n'}) {
+			payload.WriteByte('\n')
+		}
+	}
+
+	if _, err := w.send(payload.Bytes()); err != nil {
+		atomic.AddUint64(&w.stats.WriteErrors, uint64(len(batch)))
+		w.report(EventWriteError, err)
+
+		return err
+	}
+
+	atomic.AddUint64(&w.stats.Sent, uint64(len(batch)))
+
+	return nil
+}
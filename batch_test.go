@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBatchWriterSize(t *testing.T) {
+	conn := &recordConn{}
+	w := &writer{conn: conn, transport: TransportTCP, compressionType: CompressionNone}
+	b := newBatchWriter(w, 10, time.Hour)
+
+	for _, msg := range []string{"one", "two", "three"} {
+		if _, err := b.Write([]byte(msg)); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+	}
+
+	// the third message fills the batch
+	writes := conn.datagrams()
+	if len(writes) != 1 || !bytes.Equal(writes[0], []byte("one\x00two\x00three\x00")) {
+		t.Fatalf("unexpected writes %q", writes)
+	}
+
+	if _, err := b.Write([]byte("four")); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if writes = conn.datagrams(); len(writes) != 2 || string(writes[1]) != "four\x00" {
+		t.Fatalf("close doesn't flush the pending batch: %q", writes)
+	}
+
+	if stats := w.Stats(); stats.Sent != 4 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	if _, err := b.Write([]byte("five")); err != errWriterClosed {
+		t.Fatalf("unexpected error after close: %v", err)
+	}
+}
+
+func TestBatchWriterInterval(t *testing.T) {
+	conn := &recordConn{}
+	w := &writer{conn: conn, transport: TransportTCP, compressionType: CompressionNone}
+	b := newBatchWriter(w, 1<<20, 10*time.Millisecond)
+	defer b.Close()
+
+	for _, msg := range []string{"one", "two"} {
+		if _, err := b.Write([]byte(msg)); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+	}
+
+	if writes := conn.datagrams(); len(writes) != 0 {
+		t.Fatalf("batch sent before the interval: %q", writes)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(conn.datagrams()) < 1 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	if writes := conn.datagrams(); len(writes) != 1 || string(writes[0]) != "one\x00two\x00" {
+		t.Fatalf("unexpected writes %q", writes)
+	}
+}
+
+func TestBatchWriterMaxMessageBytes(t *testing.T) {
+	long := []byte(`{"version":"1.1","short_message":"` + strings.Repeat("x", 100) + `"}`)
+
+	for _, truncate := range []bool{false, true} {
+		var (
+			conn = &recordConn{}
+			ev   = &events{}
+			w    = &writer{
+				conn:              conn,
+				transport:         TransportTCP,
+				compressionType:   CompressionNone,
+				maxMessageBytes:   64,
+				truncateOversized: truncate,
+				onError:           ev.onError,
+				onDrop:            ev.onDrop,
+			}
+			b = newBatchWriter(w, 1<<20, time.Hour)
+		)
+
+		_, err := b.Write(long)
+		if !truncate {
+			if !errors.Is(err, ErrMessageTooLarge) || ev.dropped != 1 {
+				t.Fatalf("expected dropped message but got %v, %d dropped", err, ev.dropped)
+			}
+		} else if err != nil || len(ev.errors[EventTruncated]) != 1 {
+			t.Fatalf("expected truncated message but got %v, %v", err, ev.errors)
+		}
+
+		if err = b.Close(); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		var writes = conn.datagrams()
+		if !truncate {
+			if len(writes) != 0 {
+				t.Fatalf("dropped message is sent: %q", writes)
+			}
+
+			continue
+		}
+
+		if len(writes) != 1 || len(writes[0]) > 64+1 || !bytes.Contains(writes[0], []byte(truncatedMarker)) {
+			t.Fatalf("unexpected writes %q", writes)
+		}
+	}
+}
+
+func TestBatchWriterOrder(t *testing.T) {
+	conn := &recordConn{}
+	w := &writer{conn: conn, transport: TransportTCP, compressionType: CompressionNone}
+	b := newBatchWriter(w, 16, time.Millisecond)
+
+	// the interval flushes race the size flushes of the writes
+	var expected []string
+	for i := 0; i < 500; i++ {
+		msg := strconv.Itoa(i)
+		expected = append(expected, msg)
+
+		if _, err := b.Write([]byte(msg)); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+	}
+
+	if err := b.Close(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	var stream []byte
+	for _, write := range conn.datagrams() {
+		stream = append(stream, write...)
+	}
+
+	if got := strings.Split(strings.TrimSuffix(string(stream), "\x00"), "\x00"); strings.Join(got, ",") != strings.Join(expected, ",") {
+		t.Fatalf("messages out of order: %q", got)
+	}
+}
+
+func TestNewBatchUDP(t *testing.T) {
+	if _, err := New(LoggingConfiguration{GraylogAddress: "127.0.0.1:12201", BatchSize: 1 << 20}); err == nil {
+		t.Fatal("expected error for batching over UDP")
+	}
+}
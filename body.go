@@ -0,0 +1,157 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"go.uber.org/zap"
+)
+
+type (
+	// capturedBody is a request or response body captured by WithBodies, possibly truncated.
+	// It is logged as a string field the redact core understands.
+	capturedBody struct {
+		data      []byte
+		size      int64 // the size of the whole body
+		truncated bool
+	}
+
+	// bodyCapture keeps the first limit bytes written to it.
+	bodyCapture struct {
+		buf   bytes.Buffer
+		limit int
+		size  int64
+	}
+
+	// teeBody is the request body, capturing what the handler reads.
+	teeBody struct {
+		io.Reader
+		io.Closer
+	}
+
+	// captureRecorder is the ResponseRecorder capturing the response body.
+	captureRecorder struct {
+		*ResponseRecorder
+
+		body *bodyCapture
+	}
+)
+
+// newBodyCapture keeps up to limit bytes.
+func newBodyCapture(limit int) *bodyCapture {
+	return &bodyCapture{limit: limit}
+}
+
+// Write implements io.Writer, it never fails so the tee doesn't fail the handler.
+func (c *bodyCapture) Write(b []byte) (int, error) {
+	c.size += int64(len(b))
+
+	if room := c.limit - c.buf.Len(); room > 0 {
+		if len(b) > room {
+			c.buf.Write(b[:room])
+		} else {
+			c.buf.Write(b)
+		}
+	}
+
+	return len(b), nil
+}
+
+// field returns the body field, false when nothing was read or written.
+func (c *bodyCapture) field(key string) (zap.Field, bool) {
+	if c.size == 0 {
+		return zap.Field{}, false
+	}
+
+	return zap.Stringer(key, capturedBody{data: c.buf.Bytes(), size: c.size, truncated: c.size > int64(c.buf.Len())}), true
+}
+
+// Write implements http.ResponseWriter.
+func (r *captureRecorder) Write(b []byte) (int, error) {
+	n, err := r.ResponseRecorder.Write(b)
+	_, _ = r.body.Write(b[:n])
+
+	return n, err
+}
+
+// String implements fmt.Stringer. Binary bodies are replaced by their size,
+// truncated bodies end with the truncation marker.
+func (b capturedBody) String() string {
+	var data = b.data
+	if b.truncated {
+		// the limit may split the last rune
+		for i := 0; i < utf8.UTFMax-1 && len(data) > 0 && !utf8.Valid(data); i++ {
+			data = data[:len(data)-1]
+		}
+	}
+
+	if !utf8.Valid(data) || bytes.IndexByte(data, 0) >= 0 {
+		return fmt.Sprintf("[binary %d bytes]", b.size)
+	}
+
+	if b.truncated {
+		return string(data) + truncatedMarker
+	}
+
+	return string(data)
+}
+
+// redacted returns the body with the values of keys replaced in JSON and form bodies,
+// and whether any was found. scrub redacts the decoded JSON values in place.
+// Truncated bodies can't be decoded, they are redacted entirely when they contain a key.
+func (b capturedBody) redacted(keys map[string]bool, scrub func(interface{}) bool) (string, bool) {
+	if b.truncated {
+		var lower = bytes.ToLower(b.data)
+		for key := range keys {
+			if bytes.Contains(lower, []byte(key)) {
+				return redactedValue + truncatedMarker, true
+			}
+		}
+
+		return "", false
+	}
+
+	var (
+		value   interface{}
+		decoder = json.NewDecoder(bytes.NewReader(b.data))
+	)
+
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&value); err == nil {
+		if !scrub(value) {
+			return "", false
+		}
+
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", false
+		}
+
+		return string(data), true
+	}
+
+	form, err := url.ParseQuery(string(b.data))
+	if err != nil {
+		return "", false
+	}
+
+	var found bool
+	for key := range form {
+		if keys[strings.ToLower(key)] {
+			form[key] = []string{redactedValue}
+			found = true
+		}
+	}
+
+	if !found {
+		return "", false
+	}
+
+	return form.Encode(), true
+}
@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// events records the callback calls.
+type events struct {
+	mu      sync.Mutex
+	errors  map[string][]error
+	dropped int
+}
+
+func (e *events) onError(event string, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.errors == nil {
+		e.errors = make(map[string][]error)
+	}
+
+	e.errors[event] = append(e.errors[event], err)
+}
+
+func (e *events) onDrop(n int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.dropped += n
+}
+
+// failConn fails every write with a network error.
+type failConn struct {
+	net.Conn
+}
+
+func (failConn) Write([]byte) (int, error) {
+	return 0, &net.OpError{Op: "write", Net: "tcp", Err: errors.New("connection reset")}
+}
+
+func (failConn) Close() error {
+	return nil
+}
+
+func TestWriterCallbacksOversized(t *testing.T) {
+	msg := []byte(`{"short_message":"` + strings.Repeat("x", MaxChunkCount*DefaultChunkSize) + `"}`)
+
+	for _, truncate := range []bool{false, true} {
+		var e events
+		w := &writer{
+			conn:              &recordConn{},
+			transport:         TransportUDP,
+			chunkSize:         DefaultChunkSize,
+			chunkDataSize:     DefaultChunkSize - chunkHeaderSize,
+			compressionType:   CompressionNone,
+			truncateOversized: truncate,
+			retries:           1,
+			onError:           e.onError,
+			onDrop:            e.onDrop,
+		}
+
+		_, _ = w.Write(msg)
+
+		if truncate {
+			if len(e.errors[EventTruncated]) != 1 || e.dropped != 0 || !errors.Is(e.errors[EventTruncated][0], ErrTooManyChunks) {
+				t.Fatalf("unexpected truncation events %v, dropped %d", e.errors, e.dropped)
+			}
+
+			continue
+		}
+
+		if len(e.errors[EventDropped]) != 1 || e.dropped != 1 || len(e.errors[EventWriteError]) != 0 {
+			t.Fatalf("unexpected drop events %v, dropped %d", e.errors, e.dropped)
+		}
+	}
+}
+
+func TestWriterCallbacksSend(t *testing.T) {
+	var (
+		conn   = &recordConn{}
+		ids    [][]byte
+		counts []int
+		w      = &writer{
+			conn:            conn,
+			transport:       TransportUDP,
+			chunkSize:       MTUChunkSize,
+			chunkDataSize:   MTUChunkSize - chunkHeaderSize,
+			compressionType: CompressionNone,
+			onSend: func(messageID []byte, chunks int) {
+				ids = append(ids, append([]byte(nil), messageID...))
+				counts = append(counts, chunks)
+			},
+		}
+	)
+
+	if _, err := w.Write([]byte("short")); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if len(ids) != 0 {
+		t.Fatalf("unexpected callback for unchunked message %x", ids)
+	}
+
+	if _, err := w.Write([]byte(strings.Repeat("x", 2*MTUChunkSize))); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	var datagrams = conn.datagrams()[1:]
+	if len(ids) != 1 || counts[0] != len(datagrams) || len(datagrams) != 3 {
+		t.Fatalf("unexpected message ids %x of %v chunks for %d datagrams", ids, counts, len(datagrams))
+	}
+
+	for _, chunk := range datagrams {
+		if id := chunk[2:10]; string(id) != string(ids[0]) {
+			t.Fatalf("chunk id %x must be the sent id %x", id, ids[0])
+		}
+	}
+}
+
+func TestWriterCallbacksWriteError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	// nothing listens on a closed port, so reconnecting fails
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	var e events
+	w := &writer{
+		conn:            failConn{},
+		address:         addr,
+		transport:       TransportTCP,
+		compressionType: CompressionNone,
+		retries:         2,
+		onError:         e.onError,
+		onDrop:          e.onDrop,
+	}
+
+	if _, err = w.Write([]byte("lost")); err == nil {
+		t.Fatal("expected write error")
+	}
+
+	if len(e.errors[EventReconnect]) != 1 || len(e.errors[EventWriteError]) != 1 || e.dropped != 0 {
+		t.Fatalf("unexpected events %v, dropped %d", e.errors, e.dropped)
+	}
+}
+
+func TestAsyncWriterCallbacks(t *testing.T) {
+	var e events
+
+	out := &blockingWriter{release: make(chan struct{})}
+	defer close(out.release)
+
+	w := newAsyncWriter(out, 1, OverflowDropNewest)
+	w.onDrop = (&writer{onError: e.onError, onDrop: e.onDrop}).reportDrop
+
+	// the first message may be taken by the goroutine, then the second one fills the queue
+	for i := 0; i < 3; i++ {
+		_, _ = w.Write([]byte("queued"))
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.dropped == 0 || len(e.errors[EventDropped]) != e.dropped || e.errors[EventDropped][0] != errQueueFull {
+		t.Fatalf("unexpected events %v, dropped %d", e.errors, e.dropped)
+	}
+}
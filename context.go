@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
+
+// contextKey is the context key of the request-scoped logger.
+type contextKey struct{}
+
+// TraceExtractor returns the trace and span ids of ctx, empty when there is no span.
+// WithContext adds them as trace_id and span_id fields. Set it once at startup, e.g. for OpenTelemetry:
+//
+//	logger.TraceExtractor = func(ctx context.Context) (string, string) {
+//		sc := trace.SpanContextFromContext(ctx)
+//		if !sc.IsValid() {
+//			return "", ""
+//		}
+//		return sc.TraceID().String(), sc.SpanID().String()
+//	}
+var TraceExtractor func(ctx context.Context) (traceID, spanID string)
+
+// ContextWithLogger returns a copy of ctx holding log, so middleware can stash a request-scoped logger.
+func ContextWithLogger(ctx context.Context, log *zap.Logger) context.Context {
+	return context.WithValue(ctx, contextKey{}, log)
+}
+
+// WithContext returns the logger stashed by ContextWithLogger, or zap.L() when there is none,
+// with the trace_id and span_id fields when TraceExtractor finds a span in ctx.
+//
+// The apache access logger doesn't read the context, it correlates with application logs through
+// the X-Correlation-Id header. Middleware stashing the logger should add the same id as a field
+// and be wrapped by the access logger, so the id is set by the time the access line is written.
+// NewAccessLogJSON logs through the stashed logger when there is one, so its entries carry its fields.
+func WithContext(ctx context.Context) *zap.Logger {
+	log, ok := loggerFromContext(ctx)
+	if !ok {
+		log = zap.L()
+	}
+
+	return withTrace(ctx, log)
+}
+
+// loggerFromContext returns the logger stashed by ContextWithLogger.
+func loggerFromContext(ctx context.Context) (*zap.Logger, bool) {
+	log, ok := ctx.Value(contextKey{}).(*zap.Logger)
+	return log, ok
+}
+
+// withTrace adds the trace_id and span_id fields of ctx to log, see TraceExtractor.
+func withTrace(ctx context.Context, log *zap.Logger) *zap.Logger {
+	if TraceExtractor == nil {
+		return log
+	}
+
+	traceID, spanID := TraceExtractor(ctx)
+	if traceID == "" {
+		return log
+	}
+
+	return log.With(zap.String("trace_id", traceID), zap.String("span_id", spanID))
+}
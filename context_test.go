@@ -0,0 +1,47 @@
+package logger_test
+
+import (
+	"context"
+	"testing"
+
+	"go.cantor.systems/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type spanKey struct{}
+
+func TestWithContext(t *testing.T) {
+	core, logs := observer.New(zap.DebugLevel)
+	ctx := logger.ContextWithLogger(context.Background(), zap.New(core).With(zap.String("request_id", "42")))
+
+	logger.WithContext(ctx).Info("no trace")
+
+	logger.TraceExtractor = func(ctx context.Context) (string, string) {
+		if span, ok := ctx.Value(spanKey{}).(string); ok {
+			return "trace-" + span, span
+		}
+
+		return "", ""
+	}
+	defer func() { logger.TraceExtractor = nil }()
+
+	logger.WithContext(context.WithValue(ctx, spanKey{}, "1")).Info("traced")
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries but got %d", len(entries))
+	}
+
+	if fields := entries[0].ContextMap(); fields["request_id"] != "42" || fields["trace_id"] != nil {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+
+	if fields := entries[1].ContextMap(); fields["trace_id"] != "trace-1" || fields["span_id"] != "1" {
+		t.Fatalf("unexpected fields %v", fields)
+	}
+
+	if logger.WithContext(context.Background()) != zap.L() {
+		t.Fatal("expected global logger without stashed logger")
+	}
+}
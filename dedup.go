@@ -0,0 +1,256 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultDedupMax and DefaultDedupInterval are used for unset DedupConfig fields.
+const (
+	DefaultDedupMax      = 10
+	DefaultDedupInterval = 10 * time.Second
+)
+
+type (
+	// DedupConfig limits identical entries, with the same level, message and caller,
+	// to Max per Interval. The suppressed entries are summarized by a single
+	// "<message>: repeated N times in <interval>" entry once the interval is over,
+	// at the latest an interval later, on Sync or when the logger is closed.
+	// Unlike sampling, which counts entries per level and message each second,
+	// dedup keeps storms of the same error from flooding the logs, errors included.
+	DedupConfig struct {
+		// Max is how many identical entries are logged per interval, zero means DefaultDedupMax.
+		Max int
+
+		// Interval is the dedup window, zero means DefaultDedupInterval.
+		Interval time.Duration
+	}
+
+	// dedupCore drops the repeated entries over the limit.
+	dedupCore struct {
+		zapcore.Core
+
+		state *dedupState
+	}
+
+	// dedupState are the counters shared by the core and its children.
+	dedupState struct {
+		max      int
+		interval time.Duration
+		now      func() time.Time
+
+		mu        sync.Mutex
+		entries   map[dedupKey]*dedupEntry
+		lastSweep time.Time
+
+		done     chan struct{}
+		stopOnce sync.Once
+	}
+
+	// dedupKey identifies identical entries.
+	dedupKey struct {
+		level   zapcore.Level
+		message string
+		caller  string
+	}
+
+	// dedupEntry counts the entries of a key in the current window.
+	dedupEntry struct {
+		ent        zapcore.Entry
+		core       zapcore.Core
+		start      time.Time
+		count      int
+		suppressed int
+	}
+)
+
+// newDedupCore wraps core, dropping repeated entries over config.Max per config.Interval.
+// It writes the summaries of the finished windows every interval until its state is closed.
+func newDedupCore(core zapcore.Core, config DedupConfig) zapcore.Core {
+	if config.Max == 0 {
+		config.Max = DefaultDedupMax
+	}
+
+	if config.Interval == 0 {
+		config.Interval = DefaultDedupInterval
+	}
+
+	var state = &dedupState{
+		max:      config.Max,
+		interval: config.Interval,
+		now:      time.Now,
+		entries:  make(map[dedupKey]*dedupEntry),
+		done:     make(chan struct{}),
+	}
+
+	go state.run()
+
+	return &dedupCore{Core: core, state: state}
+}
+
+// With implements zapcore.Core.
+func (c *dedupCore) With(fields []zapcore.Field) zapcore.Core {
+	return &dedupCore{Core: c.Core.With(fields), state: c.state}
+}
+
+// Check implements zapcore.Core.
+func (c *dedupCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+
+	if !c.state.allow(ent, c.Core) {
+		return ce
+	}
+
+	return c.Core.Check(ent, ce)
+}
+
+// Sync implements zapcore.Core, it writes the summaries of all the suppressed entries first.
+func (c *dedupCore) Sync() error {
+	for _, s := range c.state.flush(true) {
+		s.write()
+	}
+
+	return c.Core.Sync()
+}
+
+// run writes the summaries of the finished windows every interval until s is closed,
+// so they don't wait for the next entry.
+func (s *dedupState) run() {
+	var ticker = time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-ticker.C:
+		}
+
+		for _, summary := range s.flush(false) {
+			summary.write()
+		}
+	}
+}
+
+// Close stops the periodic flush and writes the summaries of all the suppressed entries.
+func (s *dedupState) Close() error {
+	s.stopOnce.Do(func() { close(s.done) })
+
+	for _, summary := range s.flush(true) {
+		summary.write()
+	}
+
+	return nil
+}
+
+// allow counts ent and reports whether it is under the limit,
+// the summaries of finished windows are written first.
+func (s *dedupState) allow(ent zapcore.Entry, core zapcore.Core) bool {
+	var key = dedupKey{level: ent.Level, message: ent.Message}
+	if ent.Caller.Defined {
+		key.caller = ent.Caller.String()
+	}
+
+	s.mu.Lock()
+
+	var (
+		now       = s.now()
+		summaries []dedupSummary
+	)
+
+	if now.Sub(s.lastSweep) >= s.interval {
+		summaries = s.flushLocked(false, now)
+		s.lastSweep = now
+	}
+
+	e := s.entries[key]
+	if e == nil || now.Sub(e.start) >= s.interval {
+		if e != nil && e.suppressed > 0 {
+			summaries = append(summaries, e.summary(s.interval))
+		}
+
+		e = &dedupEntry{start: now}
+		s.entries[key] = e
+	}
+
+	e.ent, e.core = ent, core
+	e.count++
+
+	allowed := e.count <= s.max
+	if !allowed {
+		e.suppressed++
+	}
+
+	s.mu.Unlock()
+
+	for _, summary := range summaries {
+		summary.write()
+	}
+
+	return allowed
+}
+
+// flush returns the summaries of the finished windows, or of all of them when all is set.
+func (s *dedupState) flush(all bool) []dedupSummary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.flushLocked(all, s.now())
+}
+
+// flushLocked forgets the finished windows and returns their summaries, s.mu must be held.
+func (s *dedupState) flushLocked(all bool, now time.Time) []dedupSummary {
+	var summaries []dedupSummary
+
+	for key, e := range s.entries {
+		elapsed := now.Sub(e.start)
+		if elapsed < s.interval && !all {
+			continue
+		}
+
+		if e.suppressed > 0 {
+			if elapsed > s.interval {
+				elapsed = s.interval
+			}
+
+			summaries = append(summaries, e.summary(elapsed))
+		}
+
+		if elapsed >= s.interval {
+			delete(s.entries, key)
+		} else {
+			e.suppressed = 0
+		}
+	}
+
+	return summaries
+}
+
+// dedupSummary is the entry written for suppressed entries.
+type dedupSummary struct {
+	ent        zapcore.Entry
+	core       zapcore.Core
+	suppressed int
+}
+
+// summary returns the summary of the suppressed entries.
+func (e *dedupEntry) summary(elapsed time.Duration) dedupSummary {
+	var ent = e.ent
+	ent.Message = fmt.Sprintf("%s: repeated %d times in %s", e.ent.Message, e.suppressed, elapsed.Round(time.Millisecond))
+
+	return dedupSummary{ent: ent, core: e.core, suppressed: e.suppressed}
+}
+
+// write writes the summary to the core of the last suppressed entry. It is checked like any
+// entry, so the tees, the samplers and the cores with a higher level keep filtering it.
+func (s dedupSummary) write() {
+	if ce := s.core.Check(s.ent, nil); ce != nil {
+		ce.Write(zap.Int("repeated", s.suppressed))
+	}
+}
@@ -0,0 +1,148 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestDedupCore(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	core := newDedupCore(observed, DedupConfig{Max: 2, Interval: 10 * time.Second}).(*dedupCore)
+	defer core.state.Close()
+
+	var now = time.Unix(1000, 0)
+	core.state.now = func() time.Time { return now }
+
+	log := zap.New(core).With(zap.String("k", "v"))
+
+	for i := 0; i < 100; i++ {
+		log.Error("flapping")
+	}
+
+	log.Info("distinct")
+	log.Info("other")
+
+	if n := logs.FilterMessage("flapping").Len(); n != 2 {
+		t.Fatalf("expected 2 flapping entries but got %d", n)
+	}
+
+	if logs.Len() != 4 {
+		t.Fatalf("distinct entries are suppressed: %d", logs.Len())
+	}
+
+	// the next window summarizes the previous one
+	now = now.Add(10 * time.Second)
+	log.Error("flapping")
+
+	summaries := logs.FilterMessage("flapping: repeated 98 times in 10s").All()
+	if len(summaries) != 1 {
+		t.Fatalf("expected a summary but got %v", logs.All()[4:])
+	}
+
+	if summaries[0].Level != zapcore.ErrorLevel || summaries[0].ContextMap()["repeated"] != int64(98) ||
+		summaries[0].ContextMap()["k"] != "v" {
+		t.Fatalf("unexpected summary %v", summaries[0])
+	}
+
+	if n := logs.FilterMessage("flapping").Len(); n != 3 {
+		t.Fatalf("expected the new window to log but got %d entries", n)
+	}
+
+	// sync summarizes the current window
+	for i := 0; i < 5; i++ {
+		log.Error("flapping")
+	}
+
+	now = now.Add(time.Second)
+	if err := log.Sync(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if n := logs.FilterMessage("flapping: repeated 4 times in 1s").Len(); n != 1 {
+		t.Fatalf("expected a summary on sync but got %v", logs.All()[6:])
+	}
+
+	if n := logs.FilterMessage("flapping").Len(); n != 4 {
+		t.Fatalf("unexpected flapping entries %d", n)
+	}
+}
+
+func TestDedupCoreFlush(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	core := newDedupCore(observed, DedupConfig{Max: 1, Interval: 20 * time.Millisecond}).(*dedupCore)
+	defer core.state.Close()
+
+	log := zap.New(core)
+	for i := 0; i < 3; i++ {
+		log.Error("flapping")
+	}
+
+	// the summary is written once the window is over, without another entry
+	for deadline := time.Now().Add(time.Second); logs.FilterMessage("flapping: repeated 2 times in 20ms").Len() == 0; {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a summary but got %v", logs.All())
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestLoggerCloseDedup(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	core := newDedupCore(observed, DedupConfig{Max: 1, Interval: time.Hour}).(*dedupCore)
+
+	var now = time.Unix(1000, 0)
+	core.state.now = func() time.Time { return now }
+
+	log := &Logger{Logger: zap.New(core), dedup: core.state}
+	for i := 0; i < 3; i++ {
+		log.Error("flapping")
+	}
+
+	now = now.Add(time.Second)
+	if err := log.Close(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if n := logs.FilterMessage("flapping: repeated 2 times in 1s").Len(); n != 1 {
+		t.Fatalf("expected a summary on close but got %v", logs.All())
+	}
+}
+
+func TestNewDedupInvalid(t *testing.T) {
+	for _, config := range []DedupConfig{{Max: -1}, {Interval: -time.Second}} {
+		if _, err := New(LoggingConfiguration{Dedup: &config}); err == nil {
+			t.Fatalf("expected error for %+v", config)
+		}
+	}
+}
+
+func TestDedupCoreSummaryLevel(t *testing.T) {
+	infos, infoLogs := observer.New(zapcore.InfoLevel)
+	errs, errLogs := observer.New(zapcore.ErrorLevel)
+
+	core := newDedupCore(zapcore.NewTee(infos, errs), DedupConfig{Max: 1, Interval: time.Hour}).(*dedupCore)
+	defer core.state.Close()
+
+	log := zap.New(core)
+	for i := 0; i < 5; i++ {
+		log.Info("noisy")
+	}
+
+	if err := log.Sync(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if n := infoLogs.FilterMessageSnippet("repeated 4 times").Len(); n != 1 {
+		t.Fatalf("expected a summary but got %v", infoLogs.All())
+	}
+
+	// the summary is an info entry, the error core must not get it
+	if errLogs.Len() != 0 {
+		t.Fatalf("unexpected entries %v", errLogs.All())
+	}
+}
@@ -0,0 +1,119 @@
+package logger
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// dsnDefaultPort is the GELF port of DSNs without one.
+const dsnDefaultPort = "12201"
+
+// ParseDSN parses a DSN such as gelf+udp://graylog:12201?compression=gzip&level=6&chunk=1420
+// into a configuration, so it fits a single environment variable. The schemes are gelf+udp,
+// gelf+tcp, gelf+tls (TCP with the default TLS config), gelf+http, gelf+https and gelf+unix
+// whose path is the socket, the port defaults to 12201. The query options are:
+//
+//	compression   none, gzip, zlib, zstd or snappy
+//	level         the compression level
+//	chunk         the UDP chunk size
+//	dial_timeout  a duration such as "2s"
+//	async         a boolean such as "true"
+//	app           the app name
+//	facility      the GELF facility
+//	log_level     the minimal level such as "warn"
+//
+// It returns an error for other schemes and options or invalid values.
+func ParseDSN(dsn string) (LoggingConfiguration, error) {
+	var c LoggingConfiguration
+
+	u, err := url.Parse(dsn)
+	if err != nil {
+		return c, fmt.Errorf("invalid dsn: %w", err)
+	}
+
+	switch u.Scheme {
+	case "gelf+udp", "gelf+tcp", "gelf+tls":
+		if u.Hostname() == "" {
+			return c, fmt.Errorf("dsn %q has no host", dsn)
+		}
+
+		var port = u.Port()
+		if port == "" {
+			port = dsnDefaultPort
+		}
+
+		c.GraylogAddress = net.JoinHostPort(u.Hostname(), port)
+		c.Transport = TransportUDP
+
+		if u.Scheme != "gelf+udp" {
+			c.Transport = TransportTCP
+		}
+
+		if u.Scheme == "gelf+tls" {
+			c.TLSConfig = &tls.Config{}
+		}
+	case "gelf+http", "gelf+https":
+		if u.Host == "" {
+			return c, fmt.Errorf("dsn %q has no host", dsn)
+		}
+
+		var input = *u
+		input.Scheme = strings.TrimPrefix(u.Scheme, "gelf+")
+		input.RawQuery = ""
+		c.GraylogAddress = input.String()
+		c.Transport = TransportHTTP
+	case "gelf+unix":
+		if u.Path == "" {
+			return c, fmt.Errorf("dsn %q has no socket path", dsn)
+		}
+
+		c.GraylogAddress = u.Path
+		c.Transport = TransportUnix
+	default:
+		return c, fmt.Errorf("dsn %q has unknown scheme %q", dsn, u.Scheme)
+	}
+
+	for name, values := range u.Query() {
+		var value = values[len(values)-1]
+
+		switch name {
+		case "compression":
+			var ok bool
+			if c.CompressionType, ok = compressionNames[strings.ToLower(value)]; !ok {
+				return c, fmt.Errorf("dsn compression: unknown compression %q", value)
+			}
+		case "level":
+			c.CompressionLevel, err = strconv.Atoi(value)
+		case "chunk":
+			c.ChunkSize, err = strconv.Atoi(value)
+		case "dial_timeout":
+			c.DialTimeout, err = time.ParseDuration(value)
+		case "async":
+			c.Async, err = strconv.ParseBool(value)
+		case "app":
+			c.AppName = value
+		case "facility":
+			c.Facility = value
+		case "log_level":
+			var level zapcore.Level
+			if err = level.UnmarshalText([]byte(value)); err == nil {
+				c.Level = value
+			}
+		default:
+			return c, fmt.Errorf("dsn has unknown option %q", name)
+		}
+
+		if err != nil {
+			return c, fmt.Errorf("dsn %s: %w", name, err)
+		}
+	}
+
+	return c, nil
+}
@@ -0,0 +1,108 @@
+package logger_test
+
+import (
+	"testing"
+	"time"
+
+	"go.cantor.systems/logger"
+)
+
+func TestParseDSN(t *testing.T) {
+	for _, test := range []struct {
+		dsn      string
+		expected logger.LoggingConfiguration
+	}{
+		{
+			"gelf+udp://graylog:12201?compression=gzip&level=6&chunk=1420",
+			logger.LoggingConfiguration{
+				GraylogAddress:   "graylog:12201",
+				Transport:        logger.TransportUDP,
+				CompressionType:  logger.CompressionGzip,
+				CompressionLevel: 6,
+				ChunkSize:        1420,
+			},
+		},
+		{
+			"gelf+tcp://[::1]?app=api&facility=billing&log_level=warn&async=true&dial_timeout=2s",
+			logger.LoggingConfiguration{
+				GraylogAddress: "[::1]:12201",
+				Transport:      logger.TransportTCP,
+				AppName:        "api",
+				Facility:       "billing",
+				Level:          "warn",
+				Async:          true,
+				DialTimeout:    2 * time.Second,
+			},
+		},
+		{
+			"gelf+https://graylog.example.com/gelf?compression=none",
+			logger.LoggingConfiguration{
+				GraylogAddress:  "https://graylog.example.com/gelf",
+				Transport:       logger.TransportHTTP,
+				CompressionType: logger.CompressionNone,
+			},
+		},
+		{
+			"gelf+unix:///run/gelf.sock",
+			logger.LoggingConfiguration{GraylogAddress: "/run/gelf.sock", Transport: logger.TransportUnix},
+		},
+	} {
+		c, err := logger.ParseDSN(test.dsn)
+		if err != nil {
+			t.Fatalf("%s: %v", test.dsn, err)
+		}
+
+		if c.GraylogAddress != test.expected.GraylogAddress || c.Transport != test.expected.Transport ||
+			c.CompressionType != test.expected.CompressionType || c.CompressionLevel != test.expected.CompressionLevel ||
+			c.ChunkSize != test.expected.ChunkSize || c.AppName != test.expected.AppName ||
+			c.Facility != test.expected.Facility || c.Level != test.expected.Level ||
+			c.Async != test.expected.Async || c.DialTimeout != test.expected.DialTimeout {
+			t.Fatalf("%s: unexpected configuration %+v", test.dsn, c)
+		}
+	}
+
+	c, err := logger.ParseDSN("gelf+tls://graylog:12202")
+	if err != nil || c.TLSConfig == nil || c.Transport != logger.TransportTCP {
+		t.Fatalf("unexpected TLS configuration %+v: %v", c, err)
+	}
+
+	for _, dsn := range []string{
+		"graylog:12201",
+		"udp://graylog:12201",
+		"gelf+udp://",
+		"gelf+unix://",
+		"gelf+udp://graylog:12201?compression=lz4",
+		"gelf+udp://graylog:12201?level=high",
+		"gelf+udp://graylog:12201?chunk=",
+		"gelf+udp://graylog:12201?async=maybe",
+		"gelf+udp://graylog:12201?dial_timeout=5",
+		"gelf+udp://graylog:12201?log_level=verbose",
+		"gelf+udp://graylog:12201?retries=3",
+		"gelf+udp://graylog:%zz",
+	} {
+		if _, err := logger.ParseDSN(dsn); err == nil {
+			t.Errorf("expected error for %s", dsn)
+		}
+	}
+}
+
+func TestParseDSNNew(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	c, err := logger.ParseDSN("gelf+tcp://" + addr + "?app=api")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	log, err := logger.New(c)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("configured")
+
+	if msg := <-messages; msg["short_message"] != "configured" || msg["app_name"] != "api" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+}
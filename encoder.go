@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// facilityKey is the GELF facility field.
+	facilityKey = "facility"
+
+	// fullMessageKey is the GELF long-form message field.
+	fullMessageKey = "full_message"
+
+	// hostKey is the GELF host field.
+	hostKey = "host"
+)
+
+// gelfEncoder adds the numeric syslog level GELF expects next to the level name.
+// It keeps the last facility string field instead of encoding each one,
+// so child loggers can override the facility without duplicating the key.
+// Multiline messages are split into the first line as short_message and
+// the whole text as full_message.
+type gelfEncoder struct {
+	zapcore.Encoder
+
+	facility     string
+	callerFields bool
+	fullKey      string        // the full_message key
+	stackIsFull  bool          // the stack trace is encoded as full_message
+	hostname     func() string // replaces the host field when set
+}
+
+// newGELFEncoder creates the Graylog core JSON encoder, with the _file and _line fields
+// of the caller for configuration.CallerFields and the host of configuration.HostnameFunc.
+// The full message is encoded with the StacktraceKey of configuration.Keys when it is set.
+func newGELFEncoder(config zapcore.EncoderConfig, configuration LoggingConfiguration) zapcore.Encoder {
+	var fullKey = fullMessageKey
+	if configuration.Keys.StacktraceKey != "" {
+		fullKey = configuration.Keys.StacktraceKey
+	}
+
+	return &gelfEncoder{
+		Encoder:      zapcore.NewJSONEncoder(config),
+		callerFields: configuration.CallerFields,
+		fullKey:      fullKey,
+		stackIsFull:  config.StacktraceKey == fullKey,
+		hostname:     configuration.HostnameFunc,
+	}
+}
+
+// FullMessage sets the GELF full_message of the entry, the long-form body of the concise message,
+// e.g. log.Error("import failed", logger.FullMessage(report)). The stack trace follows it.
+func FullMessage(full string) zap.Field {
+	return zap.String(fullMessageKey, full)
+}
+
+// Clone implements zapcore.Encoder.
+func (e *gelfEncoder) Clone() zapcore.Encoder {
+	var clone = *e
+	clone.Encoder = e.Encoder.Clone()
+
+	return &clone
+}
+
+// AddString implements zapcore.ObjectEncoder.
+func (e *gelfEncoder) AddString(key, value string) {
+	if key == facilityKey {
+		e.facility = value
+		return
+	}
+
+	if key == hostKey && e.hostname != nil {
+		return
+	}
+
+	e.Encoder.AddString(key, value)
+}
+
+// EncodeEntry implements zapcore.Encoder.
+func (e *gelfEncoder) EncodeEntry(ent zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	var (
+		facility = e.facility
+		full     string
+		encoded  = make([]zapcore.Field, 0, len(fields)+5) // the caller owns fields
+	)
+
+	for _, f := range fields {
+		if f.Key == facilityKey && f.Type == zapcore.StringType {
+			facility = f.String
+			continue
+		}
+
+		if f.Key == fullMessageKey && f.Type == zapcore.StringType {
+			full = f.String
+			continue
+		}
+
+		encoded = append(encoded, f)
+	}
+
+	if i := strings.IndexByte(ent.Message, '\n'); i >= 0 {
+		if full == "" {
+			full = ent.Message
+		}
+
+		ent.Message = strings.TrimSuffix(ent.Message[:i], "\r")
+	}
+
+	if full != "" {
+		if !e.stackIsFull {
+			encoded = append(encoded, zap.String(e.fullKey, full))
+		} else if ent.Stack != "" {
+			ent.Stack = full + "\n\n" + ent.Stack
+		} else {
+			ent.Stack = full
+		}
+	}
+
+	if facility != "" {
+		encoded = append(encoded, zap.String(facilityKey, facility))
+	}
+
+	if e.hostname != nil {
+		encoded = append(encoded, zap.String(hostKey, e.hostname()))
+	}
+
+	if e.callerFields && ent.Caller.Defined {
+		var file = ent.Caller.TrimmedPath()
+		if i := strings.LastIndexByte(file, ':'); i >= 0 {
+			file = file[:i]
+		}
+
+		encoded = append(encoded, zap.String("_file", file), zap.Int("_line", ent.Caller.Line))
+	}
+
+	return e.Encoder.EncodeEntry(ent, append(encoded, zap.Int32("level", SyslogLevel(ent.Level))))
+}
+
+// SyslogLevel maps zap levels to GELF syslog severities.
+func SyslogLevel(l zapcore.Level) int32 {
+	switch l {
+	case zapcore.DebugLevel:
+		return 7 // debug
+	case zapcore.InfoLevel:
+		return 6 // informational
+	case zapcore.WarnLevel:
+		return 4 // warning
+	case zapcore.ErrorLevel:
+		return 3 // error
+	case zapcore.DPanicLevel:
+		return 2 // critical
+	case zapcore.PanicLevel:
+		return 1 // alert
+	case zapcore.FatalLevel:
+		return 0 // emergency
+	default:
+		return 6
+	}
+}
+
+// SyslogLevelEncoder encodes zap levels as GELF syslog severities,
+// for custom encoder configs whose LevelKey is the GELF `level`.
+func SyslogLevelEncoder(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+	enc.AppendInt32(SyslogLevel(l))
+}
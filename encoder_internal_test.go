@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestGELFEncoderFacility(t *testing.T) {
+	enc := newGELFEncoder(zapcore.EncoderConfig{MessageKey: "short_message"}, LoggingConfiguration{})
+	zap.String(facilityKey, "api").AddTo(enc)
+
+	child := enc.Clone()
+	zap.String(facilityKey, "billing").AddTo(child)
+
+	for _, tc := range []struct {
+		enc      zapcore.Encoder
+		fields   []zapcore.Field
+		facility string
+	}{
+		{enc, nil, `"facility":"api"`},
+		{child, nil, `"facility":"billing"`},
+		{enc, []zapcore.Field{zap.String(facilityKey, "entry")}, `"facility":"entry"`},
+	} {
+		buf, err := tc.enc.EncodeEntry(zapcore.Entry{Message: "m"}, tc.fields)
+		if err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		if line := buf.String(); strings.Count(line, `"facility"`) != 1 || !strings.Contains(line, tc.facility) {
+			t.Fatalf("expected a single %s in %s", tc.facility, line)
+		}
+	}
+}
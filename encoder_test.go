@@ -0,0 +1,115 @@
+package logger_test
+
+import (
+	"strings"
+	"testing"
+
+	"go.cantor.systems/logger"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSyslogLevel(t *testing.T) {
+	for level, expected := range map[zapcore.Level]int32{
+		zapcore.DebugLevel:  7,
+		zapcore.InfoLevel:   6,
+		zapcore.WarnLevel:   4,
+		zapcore.ErrorLevel:  3,
+		zapcore.DPanicLevel: 2,
+		zapcore.PanicLevel:  1,
+		zapcore.FatalLevel:  0,
+	} {
+		if actual := logger.SyslogLevel(level); actual != expected {
+			t.Errorf("%s: expected %d but got %d", level, expected, actual)
+		}
+	}
+}
+
+func TestNewSyslogLevelField(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Warn("warning")
+
+	msg := <-messages
+	if msg["level"] != float64(4) || msg["level_name"] != "WARN" {
+		t.Fatalf("unexpected level %v/%v", msg["level"], msg["level_name"])
+	}
+}
+
+func TestNewFacility(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+		Facility:       "api",
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("default")
+	log.WithFacility("billing").Info("child")
+
+	if msg := <-messages; msg["facility"] != "api" {
+		t.Fatalf("unexpected facility %v", msg["facility"])
+	}
+
+	if msg := <-messages; msg["facility"] != "billing" {
+		t.Fatalf("unexpected child facility %v", msg["facility"])
+	}
+
+	if _, err = logger.New(logger.LoggingConfiguration{StaticFields: map[string]string{"facility": "x"}}); err == nil {
+		t.Fatal("expected error for reserved static field")
+	}
+}
+
+func TestNewFullMessage(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress:   addr,
+		Transport:        logger.TransportTCP,
+		EnableStacktrace: true,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("import failed", logger.FullMessage("row 3: invalid date"))
+	log.Info("first line\r\nsecond line")
+	log.Info("summary\ndetails", logger.FullMessage("explicit"))
+	log.Error("failed", logger.FullMessage("report"))
+	log.Info("single line")
+
+	for _, expected := range []struct{ short, full string }{
+		{"import failed", "row 3: invalid date"},
+		{"first line", "first line\r\nsecond line"},
+		{"summary", "explicit"},
+	} {
+		msg := <-messages
+		if msg["short_message"] != expected.short || msg["full_message"] != expected.full {
+			t.Fatalf("unexpected message: %v", msg)
+		}
+	}
+
+	msg := <-messages
+	if full, _ := msg["full_message"].(string); msg["short_message"] != "failed" ||
+		!strings.HasPrefix(full, "report\n\n") || !strings.Contains(full, "TestNewFullMessage") {
+		t.Fatalf("unexpected message with stack trace: %v", msg)
+	}
+
+	if msg = <-messages; msg["short_message"] != "single line" || msg["full_message"] != nil {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+}
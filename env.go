@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Environment variables read by ConfigurationFromEnv.
+const (
+	EnvGraylogAddress   = "GRAYLOG_ADDRESS"
+	EnvGraylogTransport = "GRAYLOG_TRANSPORT"
+	EnvAppName          = "APP_NAME"
+	EnvHostname         = "HOSTNAME"
+	EnvFacility         = "LOG_FACILITY"
+	EnvLevel            = "LOG_LEVEL"
+	EnvCompression      = "LOG_COMPRESSION"
+	EnvCompressionLevel = "LOG_COMPRESSION_LEVEL"
+	EnvChunkSize        = "LOG_CHUNK_SIZE"
+	EnvDialTimeout      = "LOG_DIAL_TIMEOUT"
+	EnvAsync            = "LOG_ASYNC"
+	EnvMirrorToStdout   = "LOG_MIRROR_STDOUT"
+	EnvCaller           = "LOG_CALLER"
+	EnvStacktrace       = "LOG_STACKTRACE"
+	EnvFile             = "LOG_FILE"
+)
+
+// compressionNames are the LOG_COMPRESSION values.
+var compressionNames = map[string]int{
+	"none":   CompressionNone,
+	"gzip":   CompressionGzip,
+	"zlib":   CompressionZlib,
+	"zstd":   CompressionZstd,
+	"snappy": CompressionSnappy,
+}
+
+// NewFromEnv creates the logger from the environment, see ConfigurationFromEnv.
+func NewFromEnv() (*Logger, error) {
+	configuration, err := ConfigurationFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return New(configuration)
+}
+
+// ConfigurationFromEnv reads the configuration from the Env* variables, unset or empty ones keep
+// the New defaults. LOG_COMPRESSION is none, gzip, zlib, zstd or snappy, LOG_DIAL_TIMEOUT a duration
+// such as "2s" and the LOG_ASYNC, LOG_MIRROR_STDOUT, LOG_CALLER and LOG_STACKTRACE flags booleans
+// such as "true" or "1". It returns an error naming the variable for invalid values.
+func ConfigurationFromEnv() (LoggingConfiguration, error) {
+	var (
+		c   LoggingConfiguration
+		err error
+	)
+
+	c.GraylogAddress = os.Getenv(EnvGraylogAddress)
+	c.Transport = os.Getenv(EnvGraylogTransport)
+	c.AppName = os.Getenv(EnvAppName)
+	c.Hostname = os.Getenv(EnvHostname)
+	c.Facility = os.Getenv(EnvFacility)
+	c.Level = os.Getenv(EnvLevel)
+	c.FilePath = os.Getenv(EnvFile)
+
+	if name := os.Getenv(EnvCompression); name != "" {
+		var ok bool
+		if c.CompressionType, ok = compressionNames[strings.ToLower(name)]; !ok {
+			return c, fmt.Errorf("%s: unknown compression %q", EnvCompression, name)
+		}
+	}
+
+	for name, value := range map[string]*int{
+		EnvCompressionLevel: &c.CompressionLevel,
+		EnvChunkSize:        &c.ChunkSize,
+	} {
+		if s := os.Getenv(name); s != "" {
+			if *value, err = strconv.Atoi(s); err != nil {
+				return c, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+
+	if s := os.Getenv(EnvDialTimeout); s != "" {
+		if c.DialTimeout, err = time.ParseDuration(s); err != nil {
+			return c, fmt.Errorf("%s: %w", EnvDialTimeout, err)
+		}
+	}
+
+	for name, value := range map[string]*bool{
+		EnvAsync:          &c.Async,
+		EnvMirrorToStdout: &c.MirrorToStdout,
+		EnvCaller:         &c.EnableCaller,
+		EnvStacktrace:     &c.EnableStacktrace,
+	} {
+		if s := os.Getenv(name); s != "" {
+			if *value, err = strconv.ParseBool(s); err != nil {
+				return c, fmt.Errorf("%s: %w", name, err)
+			}
+		}
+	}
+
+	return c, nil
+}
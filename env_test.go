@@ -0,0 +1,159 @@
+package logger_test
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.cantor.systems/logger"
+)
+
+// setenv sets the variables, restored by the returned function.
+func setenv(t *testing.T, vars map[string]string) func() {
+	var restore = make(map[string]*string, len(vars))
+	for name, value := range vars {
+		if old, ok := os.LookupEnv(name); ok {
+			restore[name] = &old
+		} else {
+			restore[name] = nil
+		}
+
+		if err := os.Setenv(name, value); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+	}
+
+	return func() {
+		for name, old := range restore {
+			if old == nil {
+				_ = os.Unsetenv(name)
+			} else {
+				_ = os.Setenv(name, *old)
+			}
+		}
+	}
+}
+
+func TestConfigurationFromEnv(t *testing.T) {
+	defer setenv(t, map[string]string{
+		logger.EnvGraylogAddress:   "graylog:12201",
+		logger.EnvGraylogTransport: "tcp",
+		logger.EnvAppName:          "api",
+		logger.EnvHostname:         "node-1",
+		logger.EnvFacility:         "billing",
+		logger.EnvLevel:            "debug",
+		logger.EnvCompression:      "Zlib",
+		logger.EnvCompressionLevel: "5",
+		logger.EnvChunkSize:        "8192",
+		logger.EnvDialTimeout:      "2s",
+		logger.EnvAsync:            "true",
+		logger.EnvMirrorToStdout:   "1",
+		logger.EnvCaller:           "false",
+		logger.EnvStacktrace:       "",
+		logger.EnvFile:             "/var/log/api.log",
+	})()
+
+	c, err := logger.ConfigurationFromEnv()
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	expected := logger.LoggingConfiguration{
+		GraylogAddress:   "graylog:12201",
+		Transport:        logger.TransportTCP,
+		AppName:          "api",
+		Hostname:         "node-1",
+		Facility:         "billing",
+		Level:            "debug",
+		CompressionType:  logger.CompressionZlib,
+		CompressionLevel: 5,
+		ChunkSize:        8192,
+		DialTimeout:      2 * time.Second,
+		Async:            true,
+		MirrorToStdout:   true,
+		FilePath:         "/var/log/api.log",
+	}
+	if !reflect.DeepEqual(c, expected) {
+		t.Fatalf("expected %+v but got %+v", expected, c)
+	}
+}
+
+func TestConfigurationFromEnvInvalid(t *testing.T) {
+	for name, value := range map[string]string{
+		logger.EnvCompression:      "lz4",
+		logger.EnvCompressionLevel: "best",
+		logger.EnvChunkSize:        "1k",
+		logger.EnvDialTimeout:      "5",
+		logger.EnvAsync:            "yes please",
+	} {
+		restore := setenv(t, map[string]string{name: value})
+		_, err := logger.ConfigurationFromEnv()
+		restore()
+
+		if err == nil {
+			t.Errorf("%s=%s: expected error", name, value)
+		}
+	}
+}
+
+func TestNewFromEnv(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	defer setenv(t, map[string]string{
+		logger.EnvGraylogAddress:   addr,
+		logger.EnvGraylogTransport: logger.TransportTCP,
+		logger.EnvAppName:          "env",
+		logger.EnvLevel:            "warn",
+	})()
+
+	log, err := logger.NewFromEnv()
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("filtered")
+	log.Warn("from env")
+
+	if msg := <-messages; msg["short_message"] != "from env" || msg["app_name"] != "env" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+
+	defer setenv(t, map[string]string{logger.EnvLevel: "loud"})()
+
+	if _, err = logger.NewFromEnv(); err == nil {
+		t.Fatal("expected error for invalid level")
+	}
+}
+
+func TestNewKubernetes(t *testing.T) {
+	defer setenv(t, map[string]string{
+		logger.EnvPodName:      "api-7d4b9c-x2x5z",
+		logger.EnvPodNamespace: "billing",
+		logger.EnvNodeName:     "",
+	})()
+
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.NewWithOptions(
+		logger.WithGraylog(addr),
+		logger.WithTransport(logger.TransportTCP),
+		logger.WithKubernetes(),
+	)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("scheduled")
+
+	msg := <-messages
+	if msg["pod_name"] != "api-7d4b9c-x2x5z" || msg["namespace"] != "billing" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+
+	if _, ok := msg["node_name"]; ok {
+		t.Fatalf("unexpected empty node_name in %v", msg)
+	}
+}
@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// errorCore replaces zap.Error fields by the structured _error and _error_chain fields.
+type errorCore struct {
+	zapcore.Core
+}
+
+// newErrorCore wraps core, converting the error fields of zap.Error.
+func newErrorCore(core zapcore.Core) zapcore.Core {
+	return &errorCore{Core: core}
+}
+
+// With implements zapcore.Core.
+func (c *errorCore) With(fields []zapcore.Field) zapcore.Core {
+	return &errorCore{Core: c.Core.With(structureErrors(fields))}
+}
+
+// Check implements zapcore.Core.
+func (c *errorCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *errorCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, structureErrors(fields))
+}
+
+// structureErrors returns the fields with the error field of zap.Error replaced
+// by an _error object with the message and the type of the error, and an _error_chain array
+// with the messages of the error and the ones it wraps. Fields are copied only when needed.
+func structureErrors(fields []zapcore.Field) []zapcore.Field {
+	for i, f := range fields {
+		if f.Key != "error" || f.Type != zapcore.ErrorType {
+			continue
+		}
+
+		var structured = make([]zapcore.Field, 0, len(fields)+1)
+		structured = append(structured, fields[:i]...)
+
+		for _, f := range fields[i:] {
+			err, ok := f.Interface.(error)
+			if f.Key != "error" || f.Type != zapcore.ErrorType || !ok {
+				structured = append(structured, f)
+				continue
+			}
+
+			var chain []string
+			for e := err; e != nil; e = errors.Unwrap(e) {
+				chain = append(chain, e.Error())
+			}
+
+			structured = append(structured,
+				zap.Object("_error", zapcore.ObjectMarshalerFunc(func(enc zapcore.ObjectEncoder) error {
+					enc.AddString("message", err.Error())
+					enc.AddString("type", fmt.Sprintf("%T", err))
+					return nil
+				})),
+				zap.Strings("_error_chain", chain),
+			)
+		}
+
+		return structured
+	}
+
+	return fields
+}
@@ -0,0 +1,199 @@
+package logger
+
+import (
+	"encoding/json"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// durationSuffix is the key suffix of Duration fields.
+const durationSuffix = "_seconds"
+
+// Duration returns the field of d in float seconds, the key suffixed with "_seconds" unless it
+// already is, e.g. "db_query_seconds" for Duration("db_query", elapsed). Use it for request and
+// query timings, so Graylog dashboards get the same unit whatever encoded the duration.
+func Duration(key string, d time.Duration) zap.Field {
+	if !strings.HasSuffix(key, durationSuffix) {
+		key += durationSuffix
+	}
+
+	return zap.Float64(key, d.Seconds())
+}
+
+// Int64Field returns value as a JSON number field, so Graylog indexes it for range queries
+// whatever type it had, e.g. a string read from a header or a json.Number. Integers, floats,
+// which are truncated, and decimal strings are converted, unsigned integers and floats out of
+// the int64 range are clamped to it. Other values, which would turn the field into a string in
+// the Graylog index mapping, are skipped.
+func Int64Field(key string, value interface{}) zap.Field {
+	switch v := value.(type) {
+	case int:
+		return zap.Int64(key, int64(v))
+	case int8:
+		return zap.Int64(key, int64(v))
+	case int16:
+		return zap.Int64(key, int64(v))
+	case int32:
+		return zap.Int64(key, int64(v))
+	case int64:
+		return zap.Int64(key, v)
+	case uint:
+		return Int64Field(key, uint64(v))
+	case uint8:
+		return zap.Int64(key, int64(v))
+	case uint16:
+		return zap.Int64(key, int64(v))
+	case uint32:
+		return zap.Int64(key, int64(v))
+	case uint64:
+		if v > math.MaxInt64 {
+			return zap.Int64(key, math.MaxInt64)
+		}
+
+		return zap.Int64(key, int64(v))
+	case float32:
+		return Int64Field(key, float64(v))
+	case float64:
+		switch {
+		case math.IsNaN(v):
+			return zap.Skip()
+		case v >= math.MaxInt64:
+			return zap.Int64(key, math.MaxInt64)
+		case v <= math.MinInt64:
+			return zap.Int64(key, math.MinInt64)
+		}
+
+		return zap.Int64(key, int64(v))
+	case json.Number:
+		return Int64Field(key, string(v))
+	case string:
+		if n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64); err == nil {
+			return zap.Int64(key, n)
+		}
+
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return Int64Field(key, f)
+		}
+	}
+
+	return zap.Skip()
+}
+
+// Float64Field returns value as a JSON number field, see Int64Field.
+// Numbers and numeric strings are converted, other values are skipped.
+func Float64Field(key string, value interface{}) zap.Field {
+	switch v := value.(type) {
+	case float64:
+		return zap.Float64(key, v)
+	case float32:
+		return zap.Float64(key, float64(v))
+	case int:
+		return zap.Float64(key, float64(v))
+	case int8:
+		return zap.Float64(key, float64(v))
+	case int16:
+		return zap.Float64(key, float64(v))
+	case int32:
+		return zap.Float64(key, float64(v))
+	case int64:
+		return zap.Float64(key, float64(v))
+	case uint:
+		return zap.Float64(key, float64(v))
+	case uint8:
+		return zap.Float64(key, float64(v))
+	case uint16:
+		return zap.Float64(key, float64(v))
+	case uint32:
+		return zap.Float64(key, float64(v))
+	case uint64:
+		return zap.Float64(key, float64(v))
+	case json.Number:
+		return Float64Field(key, string(v))
+	case string:
+		if f, err := strconv.ParseFloat(strings.TrimSpace(v), 64); err == nil {
+			return zap.Float64(key, f)
+		}
+	}
+
+	return zap.Skip()
+}
+
+// BoolField returns value as a JSON boolean field, converting the strings strconv.ParseBool
+// accepts such as "true" or "0". Other values are skipped, see Int64Field.
+func BoolField(key string, value interface{}) zap.Field {
+	switch v := value.(type) {
+	case bool:
+		return zap.Bool(key, v)
+	case string:
+		if b, err := strconv.ParseBool(strings.TrimSpace(v)); err == nil {
+			return zap.Bool(key, b)
+		}
+	}
+
+	return zap.Skip()
+}
+
+// SafeFields converts fields to zap fields sorted by key, normalized to the GELF field name rules,
+// so Graylog doesn't drop them silently: characters outside of `[\w.-]` are replaced by "_",
+// reserved keys such as host or level are prefixed by "_", and id, _id and empty keys are dropped.
+// rewritten maps each changed key to its new name, or to "" when it is dropped,
+// e.g. when a key sorting before it normalizes to the same name.
+func SafeFields(fields map[string]interface{}) (safe []zap.Field, rewritten map[string]string) {
+	var keys = make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var used = make(map[string]bool, len(keys))
+
+	safe = make([]zap.Field, 0, len(keys))
+	rewritten = make(map[string]string)
+
+	for _, key := range keys {
+		var name = safeFieldName(key)
+		if name != "" && used[name] {
+			name = ""
+		}
+
+		if name != key || name == "" {
+			rewritten[key] = name
+		}
+
+		if name == "" {
+			continue
+		}
+
+		used[name] = true
+		safe = append(safe, zap.Any(name, fields[key]))
+	}
+
+	return safe, rewritten
+}
+
+// safeFieldName normalizes key, "" means the key can't be used.
+func safeFieldName(key string) string {
+	var name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	switch {
+	case name == "" || name == "id" || name == "_id":
+		return ""
+	case reservedFields[name]:
+		return "_" + name
+	default:
+		return name
+	}
+}
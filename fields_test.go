@@ -0,0 +1,162 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.cantor.systems/logger"
+)
+
+func TestSafeFields(t *testing.T) {
+	fields, rewritten := logger.SafeFields(map[string]interface{}{
+		"user_id":    1,
+		"http.path":  "/",
+		"user-agent": "test",
+		"host":       "original",
+		"_logger":    "custom",
+		"id":         "dropped",
+		"_id":        "dropped",
+		"":           "dropped",
+		"user name":  "ann",
+		"user/name":  "duplicate",
+		"größe":      2,
+	})
+
+	var keys []string
+	for _, f := range fields {
+		keys = append(keys, f.Key)
+	}
+
+	if expected := []string{"__logger", "gr__e", "_host", "http.path", "user_name", "user-agent", "user_id"}; !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("expected keys %v but got %v", expected, keys)
+	}
+
+	expected := map[string]string{
+		"host":      "_host",
+		"_logger":   "__logger",
+		"id":        "",
+		"_id":       "",
+		"":          "",
+		"user name": "user_name",
+		"user/name": "",
+		"größe":     "gr__e",
+	}
+	if !reflect.DeepEqual(rewritten, expected) {
+		t.Fatalf("expected rewritten %v but got %v", expected, rewritten)
+	}
+}
+
+func TestSafeFieldsUnchanged(t *testing.T) {
+	fields, rewritten := logger.SafeFields(map[string]interface{}{"b": 2, "a": "1"})
+
+	if len(rewritten) != 0 || len(fields) != 2 || fields[0].Key != "a" || fields[0].String != "1" {
+		t.Fatalf("unexpected fields %v, rewritten %v", fields, rewritten)
+	}
+}
+
+func TestDuration(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{GraylogAddress: addr, Transport: logger.TransportTCP})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("timed",
+		logger.Duration("query", 250*time.Millisecond),
+		logger.Duration("request_seconds", 2500*time.Millisecond),
+		logger.Duration("retry", 90*time.Second),
+	)
+
+	msg := <-messages
+	for key, expected := range map[string]float64{
+		"query_seconds":   0.25,
+		"request_seconds": 2.5,
+		"retry_seconds":   90,
+	} {
+		if msg[key] != expected {
+			t.Errorf("%s: expected %v but got %v", key, expected, msg[key])
+		}
+	}
+}
+
+func TestNumericFields(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{GraylogAddress: addr, Transport: logger.TransportTCP})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("typed",
+		logger.Int64Field("int", 42),
+		logger.Int64Field("int_string", " 42"),
+		logger.Int64Field("int_float", 42.9),
+		logger.Int64Field("int_uint", uint16(42)),
+		logger.Int64Field("int_number", json.Number("42")),
+		logger.Int64Field("int_invalid", "n/a"),
+		logger.Int64Field("int_uint_max", uint64(math.MaxUint64)),
+		logger.Int64Field("int_float_max", 1e300),
+		logger.Int64Field("int_nan", math.NaN()),
+		logger.Float64Field("float", 1.5),
+		logger.Float64Field("float_string", "1.5"),
+		logger.Float64Field("float_int", 3),
+		logger.Float64Field("float_int_max", int64(math.MaxInt64)),
+		logger.Float64Field("float_invalid", []int{1}),
+		logger.BoolField("bool", true),
+		logger.BoolField("bool_string", "true"),
+		logger.BoolField("bool_invalid", "yes"),
+		logger.BoolField("bool_int", 1),
+	)
+
+	// decode the numbers as they are encoded
+	var msg map[string]interface{}
+	raw, err := json.Marshal(<-messages)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+
+	if err = dec.Decode(&msg); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	for key, expected := range map[string]interface{}{
+		"int":           json.Number("42"),
+		"int_string":    json.Number("42"),
+		"int_float":     json.Number("42"),
+		"int_uint":      json.Number("42"),
+		"int_number":    json.Number("42"),
+		"int_uint_max":  json.Number("9223372036854776000"), // math.MaxInt64, as a float by the test server
+		"int_float_max": json.Number("9223372036854776000"),
+		"float":         json.Number("1.5"),
+		"float_string":  json.Number("1.5"),
+		"float_int":     json.Number("3"),
+		"float_int_max": json.Number("9223372036854776000"),
+		"bool":          true,
+		"bool_string":   true,
+	} {
+		if !reflect.DeepEqual(msg[key], expected) {
+			t.Errorf("%s: expected %#v but got %#v", key, expected, msg[key])
+		}
+	}
+
+	// values that aren't numbers would change the field type in the Graylog index mapping
+	for _, key := range []string{"int_invalid", "int_nan", "float_invalid", "bool_invalid", "bool_int"} {
+		if value, ok := msg[key]; ok {
+			t.Errorf("%s: expected no field but got %#v", key, value)
+		}
+	}
+
+	if _, ok := msg["pid"].(json.Number); !ok {
+		t.Errorf("pid is not a number: %#v", msg["pid"])
+	}
+}
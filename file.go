@@ -0,0 +1,20 @@
+package logger
+
+import (
+	"gopkg.in/natefinch/lumberjack.v2"
+)
+
+// newFileSink returns the rotating file output, nil without FilePath.
+// The file is opened on the first write.
+func newFileSink(configuration LoggingConfiguration) *lumberjack.Logger {
+	if configuration.FilePath == "" {
+		return nil
+	}
+
+	return &lumberjack.Logger{
+		Filename:   configuration.FilePath,
+		MaxSize:    configuration.FileMaxSize,
+		MaxBackups: configuration.FileMaxBackups,
+		MaxAge:     configuration.FileMaxAge,
+	}
+}
@@ -0,0 +1,80 @@
+package logger_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.cantor.systems/logger"
+	"go.uber.org/zap"
+)
+
+func TestNewFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the console output is kept without Graylog, keep it out of the test output
+	stderr := os.Stderr
+	if os.Stderr, err = ioutil.TempFile(dir, "console"); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer func() { os.Stderr = stderr }()
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		AppName:     "test",
+		FilePath:    filepath.Join(dir, "app.log"),
+		FileMaxSize: 1,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	// over 1 MB triggers a rotation
+	payload := strings.Repeat("x", 1024)
+	for i := 0; i < 1100; i++ {
+		log.Info("rotated", zap.String("payload", payload))
+	}
+
+	log.Info("last")
+
+	if err = log.Close(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "app*.log"))
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if len(files) != 2 {
+		t.Fatalf("expected the file and one backup but got %v", files)
+	}
+
+	f, err := os.Open(filepath.Join(dir, "app.log"))
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer f.Close()
+
+	var last map[string]interface{}
+	for scanner := bufio.NewScanner(f); scanner.Scan(); {
+		if err = json.Unmarshal(scanner.Bytes(), &last); err != nil {
+			t.Fatal("line is not JSON:", err)
+		}
+	}
+
+	if last["short_message"] != "last" || last["app_name"] != "test" || last["level"] != float64(6) {
+		t.Fatalf("unexpected GELF message: %v", last)
+	}
+
+	if _, ok := last["timestamp"].(float64); !ok {
+		t.Fatalf("GELF timestamp must be numeric but is %v", last["timestamp"])
+	}
+}
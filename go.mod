@@ -3,7 +3,10 @@ module go.cantor.systems/logger
 go 1.13
 
 require (
+	github.com/klauspost/compress v1.11.13
 	github.com/lestrrat-go/apache-logformat v2.0.4+incompatible
 	github.com/lestrrat-go/strftime v1.0.0 // indirect
-	go.uber.org/zap v1.13.0
+	go.uber.org/zap v1.16.0
+	google.golang.org/grpc v1.36.0
+	gopkg.in/natefinch/lumberjack.v2 v2.2.1
 )
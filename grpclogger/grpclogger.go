@@ -0,0 +1,126 @@
+// Package grpclogger logs gRPC calls through the Graylog logger, like the access loggers of HTTP requests.
+// It is a separate package, so services without gRPC don't link it.
+package grpclogger
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.cantor.systems/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryServerInterceptor logs each unary call with the method, code, duration_ms and peer fields,
+// at the level of CodeLevel. The handler context holds log, see logger.WithContext.
+// Handler panics are logged with the stack and returned as codes.Internal.
+func UnaryServerInterceptor(log *zap.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (resp interface{}, err error) {
+		var start = time.Now()
+
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(log, info.FullMethod, p)
+			}
+
+			logCall(ctx, log, info.FullMethod, start, err)
+		}()
+
+		return handler(logger.ContextWithLogger(ctx, log), req)
+	}
+}
+
+// StreamServerInterceptor is the stream variant of UnaryServerInterceptor, it logs when the stream ends.
+func StreamServerInterceptor(log *zap.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		stream grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) (err error) {
+		var start = time.Now()
+
+		defer func() {
+			if p := recover(); p != nil {
+				err = recovered(log, info.FullMethod, p)
+			}
+
+			logCall(stream.Context(), log, info.FullMethod, start, err)
+		}()
+
+		return handler(srv, &serverStream{
+			ServerStream: stream,
+			ctx:          logger.ContextWithLogger(stream.Context(), log),
+		})
+	}
+}
+
+// CodeLevel maps gRPC codes to levels: client errors are info, server side conditions
+// such as Unavailable are warnings, and Unknown, Unimplemented, Internal and DataLoss are errors.
+func CodeLevel(code codes.Code) zapcore.Level {
+	switch code {
+	case codes.OK, codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists, codes.Unauthenticated:
+		return zapcore.InfoLevel
+	case codes.DeadlineExceeded, codes.PermissionDenied, codes.ResourceExhausted, codes.FailedPrecondition,
+		codes.Aborted, codes.OutOfRange, codes.Unavailable:
+		return zapcore.WarnLevel
+	default:
+		return zapcore.ErrorLevel
+	}
+}
+
+// serverStream replaces the stream context.
+type serverStream struct {
+	grpc.ServerStream
+
+	ctx context.Context
+}
+
+// Context implements grpc.ServerStream.
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// recovered logs the panic p of the method handler and returns the error of the call.
+func recovered(log *zap.Logger, method string, p interface{}) error {
+	log.Error("grpc handler panic",
+		zap.String("method", method),
+		zap.String("panic", fmt.Sprint(p)),
+		zap.Stack("stacktrace"),
+	)
+
+	return status.Error(codes.Internal, "internal error")
+}
+
+// logCall writes the call entry.
+func logCall(ctx context.Context, log *zap.Logger, method string, start time.Time, err error) {
+	var code = status.Code(err)
+
+	var fields = []zap.Field{
+		zap.String("method", method),
+		zap.String("code", code.String()),
+		zap.Float64("duration_ms", float64(time.Since(start))/float64(time.Millisecond)),
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		fields = append(fields, zap.String("peer", p.Addr.String()))
+	}
+
+	if err != nil {
+		fields = append(fields, zap.Error(err))
+	}
+
+	if ce := log.Check(CodeLevel(code), method); ce != nil {
+		ce.Write(fields...)
+	}
+}
@@ -0,0 +1,176 @@
+package grpclogger_test
+
+import (
+	"context"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.cantor.systems/logger"
+	"go.cantor.systems/logger/grpclogger"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newHealthClient serves the health service through the interceptors of the observed logger.
+func newHealthClient(t *testing.T) (healthpb.HealthClient, func() []map[string]interface{}, func()) {
+	log, logs := logger.NewObserver()
+
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(grpclogger.UnaryServerInterceptor(log)),
+		grpc.StreamInterceptor(grpclogger.StreamServerInterceptor(log)),
+	)
+
+	hs := health.NewServer()
+	hs.SetServingStatus("ready", healthpb.HealthCheckResponse_SERVING)
+	healthpb.RegisterHealthServer(srv, hs)
+
+	ln := bufconn.Listen(1 << 20)
+	go func() { _ = srv.Serve(ln) }()
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return ln.Dial() }),
+		grpc.WithInsecure(),
+	)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	entries := func() []map[string]interface{} {
+		var all []map[string]interface{}
+		for _, e := range logs.All() {
+			fields := e.ContextMap()
+			fields["level"] = e.Level
+			fields["message"] = e.Message
+			all = append(all, fields)
+		}
+
+		return all
+	}
+
+	return healthpb.NewHealthClient(conn), entries, func() {
+		_ = conn.Close()
+		srv.Stop()
+	}
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	client, entries, stop := newHealthClient(t)
+	defer stop()
+
+	if _, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "ready"}); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatal("expected NotFound but got:", err)
+	}
+
+	all := entries()
+	if len(all) != 2 {
+		t.Fatalf("expected two entries but got %v", all)
+	}
+
+	const method = "/grpc.health.v1.Health/Check"
+
+	if e := all[0]; e["method"] != method || e["message"] != method || e["code"] != "OK" || e["level"] != zapcore.InfoLevel {
+		t.Fatalf("unexpected success entry %v", e)
+	}
+
+	if _, ok := all[0]["duration_ms"].(float64); !ok || all[0]["peer"] == nil {
+		t.Fatalf("missing duration or peer in %v", all[0])
+	}
+
+	if e := all[1]; e["code"] != "NotFound" || !strings.Contains(e["error"].(string), "unknown service") {
+		t.Fatalf("unexpected error entry %v", e)
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	client, entries, stop := newHealthClient(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	watch, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: "ready"})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if resp, err := watch.Recv(); err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("unexpected response %v: %v", resp, err)
+	}
+
+	cancel()
+	stop()
+
+	// the stream handler returns asynchronously after the cancellation
+	var all []map[string]interface{}
+	for deadline := time.Now().Add(time.Second); len(all) == 0 && time.Now().Before(deadline); {
+		time.Sleep(10 * time.Millisecond)
+		all = entries()
+	}
+
+	if len(all) != 1 || all[0]["method"] != "/grpc.health.v1.Health/Watch" || all[0]["code"] != "Canceled" {
+		t.Fatalf("unexpected entries %v", all)
+	}
+}
+
+func TestUnaryServerInterceptorErrors(t *testing.T) {
+	log, logs := logger.NewObserver()
+	interceptor := grpclogger.UnaryServerInterceptor(log)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}
+
+	_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+		logger.WithContext(ctx).Info("handler") // the handler context holds the logger
+		return nil, status.Error(codes.Internal, "broken")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Fatal("expected Internal but got:", err)
+	}
+
+	_, err = interceptor(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	if status.Code(err) != codes.Internal {
+		t.Fatal("expected Internal after panic but got:", err)
+	}
+
+	all := logs.All()
+	if len(all) != 4 || all[0].Message != "handler" {
+		t.Fatalf("unexpected entries %v", all)
+	}
+
+	if all[1].Level != zapcore.ErrorLevel || all[1].ContextMap()["code"] != "Internal" {
+		t.Fatalf("unexpected error entry %v", all[1])
+	}
+
+	if panicked := all[2].ContextMap(); panicked["panic"] != "boom" ||
+		!strings.Contains(panicked["stacktrace"].(string), "TestUnaryServerInterceptorErrors") {
+		t.Fatalf("unexpected panic entry %v", panicked)
+	}
+
+	if all[3].Level != zapcore.ErrorLevel || all[3].ContextMap()["code"] != "Internal" {
+		t.Fatalf("unexpected call entry %v", all[3])
+	}
+}
+
+func TestCodeLevel(t *testing.T) {
+	for code, level := range map[codes.Code]zapcore.Level{
+		codes.OK:          zapcore.InfoLevel,
+		codes.NotFound:    zapcore.InfoLevel,
+		codes.Unavailable: zapcore.WarnLevel,
+		codes.Internal:    zapcore.ErrorLevel,
+		codes.Unknown:     zapcore.ErrorLevel,
+	} {
+		if l := grpclogger.CodeLevel(code); l != level {
+			t.Errorf("%s: expected %s but got %s", code, level, l)
+		}
+	}
+}
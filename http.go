@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// httpMaxIdleConns is how many idle connections the default HTTP client keeps to the input,
+// so concurrent posts reuse them.
+const httpMaxIdleConns = 8
+
+// newHTTPClient creates the default client of the HTTP transport.
+func newHTTPClient() *http.Client {
+	var transport = http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxIdleConnsPerHost = httpMaxIdleConns
+
+	return &http.Client{Transport: transport, Timeout: DefaultHTTPTimeout}
+}
+
+// post sends the message to the Graylog HTTP input, one message per request.
+func (w *writer) post(cBytes []byte) (n int, err error) {
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+
+	if closed {
+		return 0, errWriterClosed
+	}
+
+	req, err := http.NewRequest(http.MethodPost, w.address, bytes.NewReader(cBytes))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+
+	// drain the body so the connection is reused
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return 0, fmt.Errorf("graylog http input responded %s", resp.Status)
+	}
+
+	return len(cBytes), nil
+}
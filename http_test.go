@@ -0,0 +1,232 @@
+package logger_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.cantor.systems/logger"
+)
+
+func TestNewHTTP(t *testing.T) {
+	type request struct {
+		contentType string
+		body        []byte
+	}
+
+	var requests = make(chan request, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		requests <- request{contentType: r.Header.Get("Content-Type"), body: body}
+		rw.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: srv.URL + "/gelf",
+		AppName:        "test",
+		HTTPClient:     srv.Client(),
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("posted")
+
+	req := <-requests
+	if req.contentType != "application/json" {
+		t.Fatalf("unexpected content type %q", req.contentType)
+	}
+
+	var msg map[string]interface{}
+	if err = json.Unmarshal(req.body, &msg); err != nil {
+		t.Fatalf("body is not plain JSON: %v: %q", err, req.body)
+	}
+
+	if msg["short_message"] != "posted" || msg["app_name"] != "test" || msg["version"] != "1.1" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+
+	if stats := log.Stats(); stats.Sent != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestNewHTTPAddress(t *testing.T) {
+	for _, conf := range []logger.LoggingConfiguration{
+		{GraylogAddress: "graylog:12201", Transport: logger.TransportHTTP},
+		{GraylogAddress: "ftp://graylog:12201/gelf", Transport: logger.TransportHTTP},
+		{GraylogAddress: "http://graylog:12201/gelf", Transport: logger.TransportUDP},
+	} {
+		if _, err := logger.New(conf); err == nil {
+			t.Fatalf("expected error for %+v", conf)
+		}
+	}
+}
+
+func TestNewHTTPError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	log, err := logger.New(logger.LoggingConfiguration{GraylogAddress: srv.URL + "/gelf"})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("rejected")
+
+	if stats := log.Stats(); stats.Sent != 0 || stats.WriteErrors != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestNewHTTPRetry(t *testing.T) {
+	var requests int32
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) <= 2 {
+			// drop the connection without a response, a network error for the client
+			conn, _, err := rw.(http.Hijacker).Hijack()
+			if err == nil {
+				_ = conn.Close()
+			}
+
+			return
+		}
+
+		rw.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress:  srv.URL + "/gelf",
+		WriteRetries:    2,
+		WriteRetryDelay: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("retried")
+
+	if n := atomic.LoadInt32(&requests); n != 3 {
+		t.Fatalf("expected 3 requests but got %d", n)
+	}
+
+	if stats := log.Stats(); stats.Sent != 1 || stats.WriteErrors != 0 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestNewHTTPBatch(t *testing.T) {
+	var bodies = make(chan []byte, 2)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		bodies <- body
+		rw.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: srv.URL + "/gelf",
+		BatchSize:      1 << 20,
+		BatchInterval:  time.Hour,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	log.Info("one")
+	log.Info("two")
+
+	// close sends the pending batch
+	if err = log.Close(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(string(<-bodies), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("expected one body with two lines but got %q", lines)
+	}
+
+	for i, expected := range []string{"one", "two"} {
+		var msg map[string]interface{}
+		if err = json.Unmarshal([]byte(lines[i]), &msg); err != nil || msg["short_message"] != expected {
+			t.Fatalf("unexpected line %q: %v", lines[i], err)
+		}
+	}
+}
+
+// recordingTransport records the requests sent through it.
+type recordingTransport struct {
+	requests int32
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *recordingTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	atomic.AddInt32(&t.requests, 1)
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestNewHTTPClient(t *testing.T) {
+	var conns int32
+
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusAccepted)
+	}))
+	srv.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	srv.Start()
+	defer srv.Close()
+
+	var transport = new(recordingTransport)
+
+	log, err := logger.NewWithOptions(
+		logger.WithGraylog(srv.URL+"/gelf"),
+		logger.WithHTTPClient(&http.Client{Transport: transport}),
+	)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	for i := 0; i < 3; i++ {
+		log.Info("posted")
+	}
+
+	if n := atomic.LoadInt32(&transport.requests); n != 3 {
+		t.Fatalf("unexpected recorded requests %d", n)
+	}
+
+	// the default client keeps the connection alive across posts
+	log, err = logger.New(logger.LoggingConfiguration{GraylogAddress: srv.URL + "/gelf"})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	atomic.StoreInt32(&conns, 0)
+	for i := 0; i < 3; i++ {
+		log.Info("posted")
+	}
+
+	if n := atomic.LoadInt32(&conns); n != 1 {
+		t.Fatalf("unexpected new connections %d", n)
+	}
+}
@@ -0,0 +1,152 @@
+// Package testutil provides fake Graylog inputs to assert on the GELF messages the logger sends.
+package testutil
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"testing"
+
+	"github.com/klauspost/compress/snappy"
+	"github.com/klauspost/compress/zstd"
+)
+
+// chunkHeaderSize is the size of magic bytes, message id, sequence number and sequence count.
+const chunkHeaderSize = 12
+
+var (
+	chunkedMagic = []byte{0x1e, 0x0f}
+	gzipMagic    = []byte{0x1f, 0x8b}
+	zstdMagic    = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	snappyMagic  = []byte{0xff, 0x06, 0x00, 0x00, 's', 'N', 'a', 'P', 'p', 'Y'}
+)
+
+// NewUDPGraylog starts a UDP GELF input on the loopback and returns its address and the decoded
+// messages, reassembled from their chunks and decompressed. stop closes the input and the channel,
+// it must be called before the test returns.
+func NewUDPGraylog(t testing.TB) (addr string, messages <-chan map[string]interface{}, stop func()) {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	var (
+		decoded = make(chan map[string]interface{}, 64)
+		done    = make(chan struct{})
+	)
+
+	go func() {
+		defer close(done)
+		defer close(decoded)
+
+		var (
+			buf    = make([]byte, 65535)
+			chunks = make(map[string][][]byte)
+		)
+
+		for {
+			n, _, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			payload, complete := reassemble(chunks, append([]byte(nil), buf[:n]...))
+			if !complete {
+				continue
+			}
+
+			msg, err := Decode(payload)
+			if err != nil {
+				t.Error(err)
+				continue
+			}
+
+			decoded <- msg
+		}
+	}()
+
+	return conn.LocalAddr().String(), decoded, func() {
+		_ = conn.Close()
+		<-done
+	}
+}
+
+// reassemble collects the chunks of datagram by message id, complete reports
+// whether payload is a whole message.
+func reassemble(chunks map[string][][]byte, datagram []byte) (payload []byte, complete bool) {
+	if len(datagram) < chunkHeaderSize || !bytes.HasPrefix(datagram, chunkedMagic) {
+		return datagram, true
+	}
+
+	var (
+		id           = string(datagram[2:10])
+		index, total = int(datagram[10]), int(datagram[11])
+	)
+
+	if chunks[id] == nil {
+		chunks[id] = make([][]byte, total)
+	}
+
+	if index < len(chunks[id]) {
+		chunks[id][index] = datagram[chunkHeaderSize:]
+	}
+
+	for _, chunk := range chunks[id] {
+		if chunk == nil {
+			return nil, false
+		}
+	}
+
+	payload = bytes.Join(chunks[id], nil)
+	delete(chunks, id)
+
+	return payload, true
+}
+
+// Decode decompresses a GELF payload by its magic bytes and decodes the JSON message.
+func Decode(payload []byte) (map[string]interface{}, error) {
+	var (
+		r   io.Reader
+		err error
+	)
+
+	switch {
+	case bytes.HasPrefix(payload, gzipMagic):
+		r, err = gzip.NewReader(bytes.NewReader(payload))
+	case len(payload) > 1 && payload[0]&0x0f == 0x08 && (uint16(payload[0])<<8|uint16(payload[1]))%31 == 0:
+		r, err = zlib.NewReader(bytes.NewReader(payload))
+	case bytes.HasPrefix(payload, zstdMagic):
+		var d *zstd.Decoder
+		if d, err = zstd.NewReader(bytes.NewReader(payload)); err == nil {
+			defer d.Close()
+			r = d
+		}
+	case bytes.HasPrefix(payload, snappyMagic):
+		r = snappy.NewReader(bytes.NewReader(payload))
+	default:
+		r = bytes.NewReader(payload)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("payload %q is not compressed GELF: %w", payload, err)
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("payload %q is not compressed GELF: %w", payload, err)
+	}
+
+	var msg map[string]interface{}
+	if err = json.Unmarshal(data, &msg); err != nil {
+		return nil, fmt.Errorf("message %q is not JSON: %w", data, err)
+	}
+
+	return msg, nil
+}
@@ -0,0 +1,189 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// journalSocket is the journald native protocol socket, replaced in tests.
+var journalSocket = "/run/systemd/journal/socket"
+
+type (
+	// journal is the connection to journald shared by the journal cores.
+	journal struct {
+		conn net.Conn
+	}
+
+	// journalCore writes entries to journald as native protocol fields.
+	journalCore struct {
+		zapcore.LevelEnabler
+
+		fields  []zapcore.Field
+		journal *journal
+	}
+)
+
+// newJournal connects to the journald socket, it fails when journald isn't running.
+func newJournal() (*journal, error) {
+	conn, err := net.Dial("unixgram", journalSocket)
+	if err != nil {
+		return nil, fmt.Errorf("journald is unavailable: %w", err)
+	}
+
+	return &journal{conn: conn}, nil
+}
+
+// Close closes the journald connection.
+func (j *journal) Close() error {
+	return j.conn.Close()
+}
+
+// newJournalCore creates the core writing entries enabled by level to j.
+func newJournalCore(j *journal, level zapcore.LevelEnabler) zapcore.Core {
+	return &journalCore{LevelEnabler: level, journal: j}
+}
+
+// With implements zapcore.Core.
+func (c *journalCore) With(fields []zapcore.Field) zapcore.Core {
+	var clone = *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *journalCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+// Write implements zapcore.Core. The fields are the uppercase GELF fields,
+// with MESSAGE, PRIORITY, SYSLOG_IDENTIFIER and the caller and stack trace ones.
+// Entries larger than the socket buffer are rejected by the kernel.
+func (c *journalCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	var enc = zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	// the GELF version is meaningless in the journal
+	delete(enc.Fields, "version")
+
+	var keys = make([]string, 0, len(enc.Fields))
+	for key := range enc.Fields {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	appendJournalField(&buf, "MESSAGE", ent.Message)
+	appendJournalField(&buf, "PRIORITY", strconv.Itoa(int(SyslogLevel(ent.Level))))
+
+	if ent.LoggerName != "" {
+		appendJournalField(&buf, "SYSLOG_IDENTIFIER", ent.LoggerName)
+	}
+
+	if ent.Caller.Defined {
+		appendJournalField(&buf, "CODE_FILE", ent.Caller.File)
+		appendJournalField(&buf, "CODE_LINE", strconv.Itoa(ent.Caller.Line))
+	}
+
+	if ent.Stack != "" {
+		appendJournalField(&buf, "STACKTRACE", ent.Stack)
+	}
+
+	for _, key := range keys {
+		appendJournalField(&buf, journalFieldName(key), journalFieldValue(enc.Fields[key]))
+	}
+
+	_, err := c.journal.conn.Write(buf.Bytes())
+
+	return err
+}
+
+// Sync implements zapcore.Core, datagrams are not buffered.
+func (c *journalCore) Sync() error {
+	return nil
+}
+
+// journalFieldName converts key to a journal field name: uppercase letters, digits and underscores,
+// not starting with an underscore which journald reserves for trusted fields.
+func journalFieldName(key string) string {
+	var name = strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		default:
+			return '_'
+		}
+	}, key)
+
+	name = strings.TrimLeft(name, "_")
+	if name == "" || name[0] >= '0' && name[0] <= '9' {
+		name = "FIELD_" + name
+	}
+
+	// journald limits field names to 64 bytes
+	if len(name) > 64 {
+		name = name[:64]
+	}
+
+	return name
+}
+
+// journalFieldValue formats the values of the map encoder, objects and arrays as JSON.
+func journalFieldValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]interface{}, []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+
+		return string(data)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// appendJournalField appends a field in the native protocol: NAME=value lines,
+// or the name, the little endian 64-bit size and the value for values with newlines.
+func appendJournalField(buf *bytes.Buffer, name, value string) {
+	buf.WriteString(name)
+
+	if strings.IndexByte(value, '\n') < 0 {
+		buf.WriteByte('=')
+		buf.WriteString(value)
+		buf.WriteByte('\n')
+
+		return
+	}
+
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+
+	buf.WriteByte('\n')
+	buf.Write(size[:])
+	buf.WriteString(value)
+	buf.WriteByte('\n')
+}
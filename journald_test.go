@@ -0,0 +1,149 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// newTestJournal listens on a temporary journal socket, stop restores the default one.
+func newTestJournal(t *testing.T) (conn net.PacketConn, stop func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	addr := filepath.Join(dir, "socket")
+	if conn, err = net.ListenPacket("unixgram", addr); err != nil {
+		_ = os.RemoveAll(dir)
+		t.Skip("unix datagram sockets are unsupported:", err)
+	}
+
+	socket := journalSocket
+	journalSocket = addr
+
+	return conn, func() {
+		journalSocket = socket
+		_ = conn.Close()
+		_ = os.RemoveAll(dir)
+	}
+}
+
+// parseJournalFields decodes a native protocol datagram.
+func parseJournalFields(t *testing.T, data []byte) map[string]string {
+	var fields = make(map[string]string)
+	for len(data) > 0 {
+		i := bytes.IndexAny(data, "=\n")
+		if i < 0 {
+			t.Fatalf("invalid field %q", data)
+		}
+
+		name := string(data[:i])
+		if data[i] == '=' {
+			end := bytes.IndexByte(data, '\n')
+			fields[name] = string(data[i+1 : end])
+			data = data[end+1:]
+
+			continue
+		}
+
+		size := int(binary.LittleEndian.Uint64(data[i+1 : i+9]))
+		fields[name] = string(data[i+9 : i+9+size])
+		data = data[i+9+size+1:]
+	}
+
+	return fields
+}
+
+func TestNewJournald(t *testing.T) {
+	conn, stop := newTestJournal(t)
+	defer stop()
+
+	log, err := New(LoggingConfiguration{AppName: "api", Journald: true, EnableCaller: true})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	log.Warn("first\nsecond", zap.String("request-id", "42"), zap.Int("_attempt", 3), zap.Strings("tags", []string{"a"}))
+
+	if err = log.Close(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	buf := make([]byte, 65536)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	fields := parseJournalFields(t, buf[:n])
+	for name, expected := range map[string]string{
+		"MESSAGE":           "first\nsecond",
+		"PRIORITY":          "4",
+		"SYSLOG_IDENTIFIER": "api",
+		"APP_NAME":          "api",
+		"REQUEST_ID":        "42",
+		"ATTEMPT":           "3",
+		"TAGS":              `["a"]`,
+	} {
+		if fields[name] != expected {
+			t.Errorf("%s: expected %q but got %q", name, expected, fields[name])
+		}
+	}
+
+	if fields["CODE_FILE"] == "" || fields["CODE_LINE"] == "" || fields["HOST"] == "" {
+		t.Errorf("missing fields in %v", fields)
+	}
+
+	if _, ok := fields["VERSION"]; ok {
+		t.Errorf("unexpected GELF version in %v", fields)
+	}
+}
+
+func TestNewJournaldUnavailable(t *testing.T) {
+	socket := journalSocket
+	defer func() { journalSocket = socket }()
+
+	journalSocket = filepath.Join(os.TempDir(), "missing-journal-socket")
+
+	log, err := New(LoggingConfiguration{Journald: true})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if log.journal != nil {
+		t.Fatal("journal is connected")
+	}
+
+	_ = log.Close()
+
+	if _, err = newJournal(); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("expected missing socket error but got:", err)
+	}
+}
+
+func TestJournalFieldName(t *testing.T) {
+	for key, expected := range map[string]string{
+		"app_name":   "APP_NAME",
+		"request-id": "REQUEST_ID",
+		"_id":        "ID",
+		"2fa":        "FIELD_2FA",
+		"é":          "FIELD_",
+	} {
+		if actual := journalFieldName(key); actual != expected {
+			t.Errorf("%s: expected %q but got %q", key, expected, actual)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package logger
+
+// KafkaProducer produces the messages of TransportKafka, an adapter of the Kafka client
+// of the application, so the package doesn't depend on one. It must be safe for concurrent use.
+type KafkaProducer interface {
+	// Produce sends value to topic with key, e.g. by queueing it for delivery.
+	// The producer owns key and value.
+	Produce(topic string, key, value []byte) error
+
+	// Close flushes the queued messages and closes the producer.
+	Close() error
+}
+
+// produce sends the message to the Kafka topic, keyed by the host for partition locality.
+func (w *writer) produce(cBytes []byte) (n int, err error) {
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+
+	if closed {
+		return 0, errWriterClosed
+	}
+
+	var host = w.hostname
+	if w.hostnameFunc != nil {
+		host = w.hostnameFunc()
+	}
+
+	// the payload buffer is reused once the write returns
+	if err = w.kafka.Produce(w.address, []byte(host), append([]byte(nil), cBytes...)); err != nil {
+		return 0, err
+	}
+
+	return len(cBytes), nil
+}
@@ -0,0 +1,93 @@
+package logger_test
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"go.cantor.systems/logger"
+)
+
+// kafkaMessage is a message produced to mockProducer.
+type kafkaMessage struct {
+	topic string
+	key   string
+	value map[string]interface{}
+}
+
+// mockProducer queues the produced messages until Close flushes them.
+type mockProducer struct {
+	mu      sync.Mutex
+	queued  []kafkaMessage
+	flushed []kafkaMessage
+	closed  bool
+}
+
+func (p *mockProducer) Produce(topic string, key, value []byte) error {
+	var msg = kafkaMessage{topic: topic, key: string(key)}
+	if err := json.Unmarshal(value, &msg.value); err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.queued = append(p.queued, msg)
+
+	return nil
+}
+
+func (p *mockProducer) Close() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.flushed, p.queued = append(p.flushed, p.queued...), nil
+	p.closed = true
+
+	return nil
+}
+
+func TestNewKafka(t *testing.T) {
+	var producer = &mockProducer{}
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: "gelf",
+		Transport:      logger.TransportKafka,
+		KafkaProducer:  producer,
+		Hostname:       "web-1",
+		Async:          true,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	log.Info("produced")
+
+	if err = log.Close(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	producer.mu.Lock()
+	defer producer.mu.Unlock()
+
+	if !producer.closed || len(producer.flushed) != 1 {
+		t.Fatalf("expected one message flushed on close but got %+v", producer.flushed)
+	}
+
+	if msg := producer.flushed[0]; msg.topic != "gelf" || msg.key != "web-1" ||
+		msg.value["short_message"] != "produced" || msg.value["host"] != "web-1" || msg.value["version"] != logger.GELFVersion {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+}
+
+func TestNewKafkaConfiguration(t *testing.T) {
+	for _, configuration := range []logger.LoggingConfiguration{
+		{GraylogAddress: "gelf", Transport: logger.TransportKafka},
+		{GraylogAddress: "gelf", Transport: logger.TransportKafka, KafkaProducer: &mockProducer{}, BatchSize: 10},
+		{GraylogAddress: "gelf", Transport: logger.TransportKafka, KafkaProducer: &mockProducer{}, FailoverAddresses: []string{"logs"}},
+	} {
+		if _, err := logger.New(configuration); err == nil {
+			t.Fatalf("expected error for configuration %+v", configuration)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package logger
+
+import (
+	"fmt"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// KeyNames overrides the keys of the entry fields in the Graylog and console messages,
+// for Graylog setups with custom field mappings. Empty keys keep the defaults:
+// short_message, full_message, level_name, timestamp, _logger and _caller.
+// Messages without short_message aren't GELF, so renaming it requires a Graylog input accepting them.
+type KeyNames struct {
+	// MessageKey replaces short_message.
+	MessageKey string
+	// StacktraceKey replaces full_message, the stack trace and the FullMessage text.
+	StacktraceKey string
+	// LevelKey replaces level_name, the numeric GELF level stays level.
+	LevelKey string
+	// TimeKey replaces timestamp.
+	TimeKey string
+	// NameKey replaces _logger.
+	NameKey string
+	// CallerKey replaces _caller.
+	CallerKey string
+}
+
+// gelfKeys are the GELF fields the key names can't replace.
+var gelfKeys = map[string]bool{
+	"version":   true,
+	"host":      true,
+	"level":     true,
+	facilityKey: true,
+}
+
+// apply sets the overridden keys of config.
+func (k KeyNames) apply(config *zapcore.EncoderConfig) {
+	for _, key := range []struct {
+		dst *string
+		key string
+	}{
+		{&config.MessageKey, k.MessageKey},
+		{&config.StacktraceKey, k.StacktraceKey},
+		{&config.LevelKey, k.LevelKey},
+		{&config.TimeKey, k.TimeKey},
+		{&config.NameKey, k.NameKey},
+		{&config.CallerKey, k.CallerKey},
+	} {
+		if key.key != "" {
+			*key.dst = key.key
+		}
+	}
+}
+
+// validate checks that the overridden keys are valid GELF field names
+// which don't replace the GELF fields or another entry key.
+func (k KeyNames) validate() error {
+	var config = zapcore.EncoderConfig{
+		MessageKey:    "short_message",
+		StacktraceKey: fullMessageKey,
+		LevelKey:      "level_name",
+		TimeKey:       "timestamp",
+		NameKey:       "_logger",
+		CallerKey:     "_caller",
+	}
+
+	k.apply(&config)
+
+	var used = make(map[string]bool, 6)
+	for _, key := range []string{
+		config.MessageKey, config.StacktraceKey, config.LevelKey, config.TimeKey, config.NameKey, config.CallerKey,
+	} {
+		if used[key] {
+			return fmt.Errorf("key %q is used twice", key)
+		}
+
+		used[key] = true
+	}
+
+	for _, key := range []string{k.MessageKey, k.StacktraceKey, k.LevelKey, k.TimeKey, k.NameKey, k.CallerKey} {
+		switch {
+		case key == "":
+		case !validKeyName(key):
+			return fmt.Errorf("key %q isn't a valid GELF field name", key)
+		case gelfKeys[key]:
+			return fmt.Errorf("key %q is a GELF field", key)
+		}
+	}
+
+	return nil
+}
+
+// validKeyName reports whether key only has the characters of GELF field names.
+func validKeyName(key string) bool {
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '.', r == '-':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
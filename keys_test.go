@@ -0,0 +1,85 @@
+package logger_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.cantor.systems/logger"
+)
+
+func TestNewKeys(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress:   addr,
+		Transport:        logger.TransportTCP,
+		AppName:          "api",
+		EnableCaller:     true,
+		EnableStacktrace: true,
+		Keys: logger.KeyNames{
+			MessageKey:    "message",
+			StacktraceKey: "message_long",
+			LevelKey:      "severity",
+			TimeKey:       "ts",
+			NameKey:       "_name",
+			CallerKey:     "_source",
+		},
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Error("failed\ndetails")
+
+	msg := <-messages
+	for _, key := range []string{"short_message", "full_message", "level_name", "timestamp", "_logger", "_caller"} {
+		if _, ok := msg[key]; ok {
+			t.Fatalf("default key %s must be replaced: %v", key, msg)
+		}
+	}
+
+	if msg["message"] != "failed" || msg["severity"] != "ERROR" || msg["_name"] != "api" || msg["level"] != float64(3) ||
+		msg["version"] != logger.GELFVersion || msg["host"] == nil {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+
+	if _, ok := msg["ts"].(float64); !ok || !strings.Contains(fmt.Sprint(msg["_source"]), "keys_test.go:") ||
+		!strings.HasPrefix(fmt.Sprint(msg["message_long"]), "failed\ndetails\n\n") {
+		t.Fatalf("unexpected time, caller or full message: %v", msg)
+	}
+
+	for _, keys := range []logger.KeyNames{
+		{MessageKey: "full_message"},
+		{MessageKey: "version"},
+		{LevelKey: "host"},
+		{TimeKey: "level"},
+		{NameKey: "bad key"},
+		{CallerKey: "short_message"},
+	} {
+		if _, err = logger.New(logger.LoggingConfiguration{Keys: keys}); err == nil {
+			t.Fatalf("expected error for keys %+v", keys)
+		}
+	}
+}
+
+func TestNewDefaultKeys(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+		Keys:           logger.KeyNames{CallerKey: "_caller"},
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("defaults")
+
+	if msg := <-messages; msg["short_message"] != "defaults" || msg["level_name"] != "INFO" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+}
@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+// The downward API environment variables read by LoggingConfiguration.Kubernetes, e.g.
+//
+//	env:
+//	- name: POD_NAME
+//	  valueFrom:
+//	    fieldRef:
+//	      fieldPath: metadata.name
+const (
+	EnvPodName      = "POD_NAME"
+	EnvPodNamespace = "POD_NAMESPACE"
+	EnvNodeName     = "NODE_NAME"
+)
+
+// kubernetesNamespaceFile is the namespace of the service account mounted in pods.
+const kubernetesNamespaceFile = "/var/run/secrets/kubernetes.io/serviceaccount/namespace"
+
+// kubernetesFields returns the pod_name, namespace and node_name fields which are set,
+// the namespace falls back to the one of the service account.
+func kubernetesFields() []zap.Field {
+	var fields = make([]zap.Field, 0, 3)
+	if name := os.Getenv(EnvPodName); name != "" {
+		fields = append(fields, zap.String("pod_name", name))
+	}
+
+	var namespace = os.Getenv(EnvPodNamespace)
+	if namespace == "" {
+		if data, err := ioutil.ReadFile(kubernetesNamespaceFile); err == nil {
+			namespace = strings.TrimSpace(string(data))
+		}
+	}
+
+	if namespace != "" {
+		fields = append(fields, zap.String("namespace", namespace))
+	}
+
+	if node := os.Getenv(EnvNodeName); node != "" {
+		fields = append(fields, zap.String("node_name", node))
+	}
+
+	return fields
+}
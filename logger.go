@@ -4,14 +4,27 @@ import (
 	"bytes"
 	"compress/gzip"
 	"compress/zlib"
+	"context"
 	"crypto/rand"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
+	mathrand "math/rand"
 	"net"
+	"net/http"
+	"net/url"
 	"os"
-	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
@@ -19,22 +32,337 @@ import (
 type (
 	LoggingConfiguration struct {
 		GraylogAddress string
-		AppName        string
-		Hostname       string
+
+		// FailoverAddresses are collectors used in turn when the current one is unreachable,
+		// with the transport of GraylogAddress, which isn't HTTP. A failed over writer retries
+		// GraylogAddress every failbackInterval. TCP and unix connections detect a down collector
+		// when connecting, UDP ones only by write errors such as refused datagrams, so a UDP writer
+		// may fail back to a down primary until its writes fail again.
+		FailoverAddresses []string
+
+		// AppName is the app_name field and the logger name, the GELF _logger field.
+		AppName string
+
+		// Hostname is the GELF host field, it defaults to os.Hostname().
+		Hostname string
+
+		// HostnameFunc returns the GELF host field of each message of the GELF outputs instead of
+		// Hostname, e.g. the current node name after a live migration. It is called for every message,
+		// so it should be cheap or cache the value. The other outputs keep Hostname.
+		HostnameFunc func() string
+
+		// Facility is the GELF facility field categorizing the messages, it is omitted when empty.
+		// Child loggers override it with Logger.WithFacility.
+		Facility string
+
+		// Transport is the network used to reach Graylog,
+		// TransportUDP (default), TransportTCP, TransportUnix, TransportHTTP or TransportKafka.
+		Transport string
+
+		// HTTPClient posts the messages of the HTTP transport, it defaults to a keep-alive client
+		// with the proxy of the environment and DefaultHTTPTimeout.
+		// Configure timeouts, proxies and TLS for HTTPS inputs on the client.
+		HTTPClient *http.Client
+
+		// KafkaProducer produces the messages of TransportKafka, which it requires.
+		// Close closes it after flushing the queued messages.
+		KafkaProducer KafkaProducer
+
+		// CompressionThreshold is the message size below which messages are sent uncompressed,
+		// zero means DefaultCompressionThreshold and a negative value compresses every message.
+		// Graylog detects uncompressed messages, short ones usually grow when compressed.
+		CompressionThreshold int
+
+		// TLSConfig enables TLS for the TCP transport, which becomes the default transport when set.
+		// Use ServerName to verify the collector name and RootCAs for self-signed internal CAs.
+		TLSConfig *tls.Config
+
+		// CompressionType is one of CompressionNone, CompressionGzip (default), CompressionZlib, CompressionZstd
+		// or CompressionSnappy.
+		CompressionType int
+
+		// CompressionLevel is the codec compression level,
+		// zero means gzip.BestCompression for gzip and zlib, zstd.SpeedDefault for zstd.
+		// New rejects levels out of the codec range, it is ignored with CompressionNone.
+		CompressionLevel int
+
+		// RawGELF sends uncompressed GELF JSON, it overrides CompressionType and CompressionLevel.
+		RawGELF bool
+
+		// Level is the minimal enabled level: "debug", "info" (default), "warn", "error", etc.
+		Level string
+
+		// ConsoleTimeEncoder encodes the console timestamps, it defaults to zapcore.ISO8601TimeEncoder.
+		// Graylog messages always have the numeric GELF timestamp.
+		ConsoleTimeEncoder zapcore.TimeEncoder
+
+		// Clock returns the encoded timestamps of the console and Graylog messages instead of
+		// the entry time, e.g. to pin the GELF timestamp in golden-file tests. Nil means time.Now.
+		Clock func() time.Time
+
+		// MirrorToStdout keeps the console output when Graylog is used,
+		// so logs are also visible in e.g. `kubectl logs`.
+		MirrorToStdout bool
+
+		// FailOnGraylogError makes New return a *GraylogError when Graylog is unreachable,
+		// instead of falling back to stdout.
+		FailOnGraylogError bool
+
+		// EnableCaller adds the calling file and line as "_caller" field.
+		EnableCaller bool
+
+		// CallerFields adds the calling file and line as separate "_file" and numeric "_line" fields
+		// next to "_caller", so Graylog can filter and aggregate on them. It requires EnableCaller.
+		CallerFields bool
+
+		// CallerSkip is the number of stack frames to skip for "_caller", e.g. 1 when messages
+		// are logged by an internal facade, so the field points at its caller. It requires EnableCaller.
+		CallerSkip int
+
+		// EnableStacktrace adds the stack trace of error and more severe entries as "full_message",
+		// after the FullMessage or multiline message text.
+		EnableStacktrace bool
+
+		// StructuredErrors replaces the error field of zap.Error in Graylog messages by an _error object
+		// with the message and the type of the error, and an _error_chain array with the messages
+		// of the wrapped errors, e.g. to group alerts by the root cause.
+		StructuredErrors bool
+
+		// TruncateOversized shortens the longest fields of UDP messages that need more than MaxChunkCount chunks,
+		// marking them with "...[truncated]", instead of failing to send them.
+		TruncateOversized bool
+
+		// MaxMessageBytes limits the size of the uncompressed messages, checked before compressing
+		// them to cap the memory and CPU spent on e.g. a logged HTTP body: longer messages are dropped,
+		// or truncated when TruncateOversized is set, and reported to OnError. Zero means unlimited.
+		MaxMessageBytes int
+
+		// RedactKeys are field keys whose values are replaced by "***", matched case-insensitively
+		// at any depth of objects and structs, e.g. "token" or "password".
+		RedactKeys []string
+
+		// RedactDefaults adds DefaultRedactKeys to RedactKeys.
+		RedactDefaults bool
+
+		// Sampling limits repeated Graylog entries below error level each second, see newSamplerCore.
+		// Nil disables sampling, zero fields use DefaultSamplingInitial and DefaultSamplingThereafter.
+		Sampling *zap.SamplingConfig
+
+		// Sentry forwards error entries to Sentry, see SentryConfig. Nil disables it.
+		Sentry *SentryConfig
+
+		// OTel emits entries as OpenTelemetry log records, see OTelConfig. Nil disables it.
+		OTel *OTelConfig
+
+		// Sinks are extra outputs of the GELF JSON entries, each with its minimal level, see Sink.
+		Sinks []Sink
+
+		// Dedup limits identical entries of all outputs per interval, see DedupConfig. Nil disables it.
+		Dedup *DedupConfig
+
+		// NthSampling logs the first and every Nth entry of a message to all outputs,
+		// see NthSamplingConfig. Nil disables it.
+		NthSampling *NthSamplingConfig
+
+		// StaticFields are attached to every message, e.g. environment or git_commit.
+		// Keys must not be one of the reserved GELF or logger fields.
+		StaticFields map[string]string
+
+		// Kubernetes adds the pod_name, namespace and node_name fields of the pod, read from the
+		// EnvPodName, EnvPodNamespace and EnvNodeName downward API variables, the namespace
+		// falling back to the service account one. Missing values are omitted.
+		Kubernetes bool
+
+		// FieldPrefix is prepended to the keys of the fields of the GELF outputs (Graylog, the file
+		// and the sinks), e.g. "app." for the app.user_id field, except the reserved GELF and logger
+		// fields such as host or app_name. Keys of nested objects are kept.
+		FieldPrefix string
+
+		// Keys overrides the keys of the message, stack trace, level name, time, logger name and caller.
+		Keys KeyNames
+
+		// OmitFields are injected fields left out of the messages, e.g. to save Graylog index fields:
+		// "pid" and "exe" can be omitted, the GELF host and version fields and app_name can't.
+		OmitFields []string
+
+		// Async sends messages to Graylog from a background goroutine, so logging never blocks.
+		// Messages logged while the queue is full are dropped unless OverflowPolicy is OverflowBlock.
+		Async bool
+
+		// QueueSize is the async queue capacity, zero means DefaultQueueSize.
+		QueueSize int
+
+		// OverflowPolicy handles the messages logged while the async queue is full,
+		// it defaults to OverflowDropNewest. The dropped messages are counted by Logger.Dropped
+		// and reported to OnDrop.
+		OverflowPolicy OverflowPolicy
+
+		// BatchSize enables batching, messages are buffered until they reach BatchSize bytes
+		// or BatchInterval has passed since the first one. Stream transports send a batch
+		// with a single write, HTTP as a newline-delimited body for inputs accepting it.
+		// UDP and Kafka don't support it, each message is a datagram or a record.
+		// Zero disables batching.
+		BatchSize int
+
+		// BatchInterval is the maximal delay of batched messages, zero means DefaultBatchInterval.
+		BatchInterval time.Duration
+
+		// ChunkSize is the maximal UDP datagram size, zero means DefaultChunkSize.
+		// Use bigger chunks (e.g. 8192) on LANs with jumbo frames.
+		ChunkSize int
+
+		// Journald writes the entries to the systemd journal in its native protocol, the fields
+		// uppercased so they are queryable with journalctl, e.g. `journalctl APP_NAME=api`.
+		// It replaces the console output unless Graylog or MirrorToStdout keep it, and the console
+		// is used when the journal socket is absent.
+		Journald bool
+
+		// FilePath adds a file output with the Graylog JSON messages, one per line,
+		// rotated by size and age. The console output is kept unless Graylog is used
+		// without MirrorToStdout, like without a file.
+		FilePath string
+
+		// FileMaxSize is the file size in megabytes triggering a rotation, zero means 100.
+		FileMaxSize int
+
+		// FileMaxBackups is how many rotated files are kept, zero keeps all of them.
+		FileMaxBackups int
+
+		// FileMaxAge is how many days rotated files are kept, zero keeps them regardless of age.
+		FileMaxAge int
+
+		// WriteRetries is how many times a TCP, unix or HTTP write failing with a network error is retried,
+		// zero means DefaultWriteRetries and a negative value retries only once like UDP writes.
+		WriteRetries int
+
+		// WriteRetryDelay is the base delay between write retries, zero means DefaultWriteRetryDelay.
+		// The first retry is immediate, the following ones back off exponentially with jitter,
+		// so senders don't reconnect all at once when the collector recovers.
+		WriteRetryDelay time.Duration
+
+		// DialTimeout limits connecting to Graylog, zero means DefaultDialTimeout.
+		// It bounds how long New blocks before falling back when the collector is down.
+		DialTimeout time.Duration
+
+		// WriteTimeout limits each TCP and unix write, so a collector that stops reading can't block
+		// the logging goroutine, a timed out write fails like a network error and reconnects.
+		// Zero means DefaultWriteTimeout and a negative value disables it, UDP and HTTP ignore it.
+		WriteTimeout time.Duration
+
+		// OnError is called on notable Graylog output events, see the Event constants, e.g. to count them
+		// without a metrics dependency. err is the cause, such as the write error triggering a reconnect.
+		// It is called synchronously from the writing goroutine, so it must return quickly, e.g. by
+		// incrementing a counter, and must not log through this logger.
+		OnError func(event string, err error)
+
+		// OnDrop is called with the number of messages dropped without being sent,
+		// because the async queue is full or they need too many chunks. Like OnError it must not block.
+		OnDrop func(n int)
+
+		// OnSend is called after a chunked UDP message is sent, with its 8 bytes GELF message ID
+		// and its number of chunks, e.g. to log the IDs locally when debugging the delivery.
+		// messageID is only valid during the call. Like OnError it must not block.
+		OnSend func(messageID []byte, chunks int)
+
+		// FallbackBuffer keeps up to this many messages when Graylog is unreachable at startup,
+		// then the logger dials it in the background and replays them once connected, so the early
+		// startup logs reach Graylog. The console is used until then. Zero disables it, BatchSize
+		// doesn't apply to the replaying logger.
+		FallbackBuffer int
+
+		// FallbackBufferBytes limits the size of the FallbackBuffer messages,
+		// zero means DefaultFallbackBufferBytes. The oldest messages are dropped beyond the limits.
+		FallbackBufferBytes int
+	}
+
+	// GraylogError reports that the Graylog connection could not be established.
+	GraylogError struct {
+		Address string
+		Err     error
+	}
+
+	// Stats are the Graylog output counters.
+	// To export them to Prometheus wrap them in counter funcs, e.g.
+	// prometheus.NewCounterFunc(prometheus.CounterOpts{Name: "gelf_messages_sent_total"}, func() float64 {
+	// 	return float64(log.Stats().Sent)
+	// }).
+	Stats struct {
+		// Sent is the number of messages written to Graylog.
+		Sent uint64
+		// Dropped is the number of messages discarded without trying to send,
+		// because they need more than MaxChunkCount chunks or the async queue is full.
+		Dropped uint64
+		// WriteErrors is the number of messages that failed to be written.
+		WriteErrors uint64
+	}
+
+	// Logger is a zap.Logger bound to the Graylog connection it writes to.
+	Logger struct {
+		*zap.Logger
+
+		level   zap.AtomicLevel
+		writer  io.WriteCloser
+		gelf    *writer
+		async   *asyncWriter
+		replay  *replayWriter
+		file    io.Closer
+		journal io.Closer
+		dedup   io.Closer
+		outputs []zapcore.Core // the Sentry, OTel and sink cores
+		dialErr error          // the Graylog dial error of the stdout fallback
+
+		components sync.Map // component name to *zap.Logger
 	}
 
-	// implement io.Writer
+	// implement io.WriteCloser.
+	// writer is safe for concurrent use, the chunks or the frame of a message are written
+	// to the connection without interleaving with other messages.
 	writer struct {
-		conn             net.Conn
+		stats     Stats  // accessed atomically, first for 64-bit alignment
+		idCounter uint64 // accessed atomically
+		idSeed    uint64
+
+		writeMu  sync.Mutex // serializes the writes of a message to the connection
+		mu       sync.Mutex // guards conn, closed and redialAt
+		conn     net.Conn
+		closed   bool
+		redialAt time.Time
+
+		address          string   // the current collector, guarded by mu after dialing
+		addresses        []string // the collectors in failover order, GraylogAddress first
+		current          int      // index of address, guarded by mu
+		failbackAt       time.Time
+		probing          bool // a failback dial is running, guarded by mu
+		transport        string
+		tlsConfig        *tls.Config
+		httpClient       *http.Client
+		kafka            KafkaProducer
 		chunkSize        int
 		chunkDataSize    int
 		compressionType  int
 		compressionLevel int
+		compressionMin   int
+		compressors      sync.Pool
+
+		truncateOversized bool
+		maxMessageBytes   int
+		retries           int
+		retryDelay        time.Duration
+		dialTimeout       time.Duration
+		writeTimeout      time.Duration
+		hostname          string
+		hostnameFunc      func() string
+		pinger            pinger
+
+		onError func(event string, err error)
+		onDrop  func(n int)
+		onSend  func(messageID []byte, chunks int)
 	}
 
-	// implement io.WriteCloser.
-	writeCloser struct {
-		bytes.Buffer
+	// resetWriteCloser is a compressor that can be reused for another destination.
+	resetWriteCloser interface {
+		io.WriteCloser
+		Reset(io.Writer)
 	}
 )
 
@@ -43,28 +371,182 @@ const (
 	// See http://docs.graylog.org/en/2.4/pages/gelf.html.
 	MaxChunkCount = 128
 
-	// DefaultChunkSize is default WAN chunk size.
+	// GELFVersion is the GELF spec version sent in every message,
+	// Graylog requires the version field to be exactly this string.
+	GELFVersion = "1.1"
+
+	// DefaultChunkSize is default WAN chunk size, it fits the 1500 bytes Ethernet MTU
+	// with the IP and UDP headers and some room for tunnels, e.g. VPNs.
+	// Paths with a lower MTU reject larger datagrams with EMSGSIZE, they are resent in MTUChunkSize chunks.
 	DefaultChunkSize = 1420
 
+	// DefaultHTTPTimeout is the default timeout of a post of the HTTP transport.
+	DefaultHTTPTimeout = 10 * time.Second
+
+	// MTUChunkSize is the chunk size of messages rejected with EMSGSIZE,
+	// the UDP payload every IPv4 host must accept without fragmentation.
+	MTUChunkSize = 508
+
+	// DefaultCompressionThreshold is default size below which messages are not compressed.
+	DefaultCompressionThreshold = 512
+
+	// DefaultQueueSize is default async queue capacity.
+	DefaultQueueSize = 1024
+
+	// DefaultBatchInterval is default maximal delay of batched messages.
+	DefaultBatchInterval = time.Second
+
+	// DefaultWriteRetries is default number of write retries for the stream transports.
+	DefaultWriteRetries = 3
+
+	// DefaultWriteRetryDelay is default base delay between write retries.
+	DefaultWriteRetryDelay = 50 * time.Millisecond
+
+	// DefaultDialTimeout is default Graylog connection timeout.
+	DefaultDialTimeout = 5 * time.Second
+
+	// DefaultWriteTimeout is default timeout of the TCP and unix writes.
+	DefaultWriteTimeout = 5 * time.Second
+
+	// MaxChunkSize is maximal chunk size, the UDP datagram payload limit.
+	MaxChunkSize = 65507
+
+	// redialAttempts is how many times a failed connection is re-dialed before giving up.
+	redialAttempts = 3
+
+	// redialBackoff is the delay increment between re-dial attempts.
+	redialBackoff = 100 * time.Millisecond
+
+	// redialCooldown is how long to wait after exhausting re-dial attempts before trying again.
+	redialCooldown = 5 * time.Second
+
+	// chunkHeaderSize is the size of magic bytes, message id, sequence number and sequence count.
+	chunkHeaderSize = 12
+
+	// CompressionDefault use the default compression (gzip).
+	CompressionDefault = 0
+
 	// CompressionNone don't use compression.
-	CompressionNone = 0
+	CompressionNone = -1
 
 	// CompressionGzip use gzip compression.
 	CompressionGzip = 1
 
 	// CompressionZlib use zlib compression.
 	CompressionZlib = 2
+
+	// CompressionZstd use zstd compression, levels are zstd.SpeedFastest to zstd.SpeedBestCompression.
+	CompressionZstd = 3
+
+	// CompressionSnappy use the snappy framing format, which has no levels.
+	// It is faster than gzip but compresses less and requires building with the snappy tag.
+	CompressionSnappy = 4
+
+	// TransportUDP send chunked GELF messages over UDP.
+	TransportUDP = "udp"
+
+	// TransportTCP send null byte terminated GELF messages over TCP.
+	// Graylog does not accept compressed TCP messages, so compression is always disabled.
+	TransportTCP = "tcp"
+
+	// TransportUnix send null byte terminated GELF messages over a unix stream socket,
+	// e.g. to a local log forwarder. Compression is disabled like for TCP.
+	// GraylogAddress is the socket path, optionally prefixed by unix://.
+	TransportUnix = "unix"
+
+	// TransportHTTP posts every GELF message to a Graylog HTTP input, without chunking and compression.
+	// GraylogAddress is the input URL, e.g. http://graylog:12201/gelf, URLs starting with
+	// http:// or https:// select the transport by themselves.
+	TransportHTTP = "http"
+
+	// TransportKafka produces every GELF message to a Kafka topic with KafkaProducer,
+	// keyed by the host. GraylogAddress is the topic, compression is left to the producer.
+	TransportKafka = "kafka"
+
+	// EventWriteError is the OnError event of a failed message write.
+	EventWriteError = "write_error"
+
+	// EventReconnect is the OnError event of a reconnection after a network error.
+	EventReconnect = "reconnect"
+
+	// EventTruncated is the OnError event of an oversized message truncated by TruncateOversized.
+	EventTruncated = "truncated"
+
+	// EventDropped is the OnError event of a dropped message, next to the OnDrop call.
+	EventDropped = "dropped"
+
+	// unixScheme prefixes unix socket addresses.
+	unixScheme = "unix://"
 )
 
 var (
 	// chunkedMagicBytes chunked message magic bytes.
 	// See http://docs.graylog.org/en/2.4/pages/gelf.html.
 	chunkedMagicBytes = []byte{0x1e, 0x0f}
+
+	// reservedFields can't be overridden by StaticFields.
+	reservedFields = map[string]bool{
+		"version":       true,
+		"host":          true,
+		"short_message": true,
+		"full_message":  true,
+		"timestamp":     true,
+		"level":         true,
+		"level_name":    true,
+		"facility":      true,
+		"line":          true,
+		"file":          true,
+		"id":            true,
+		"_id":           true,
+		"_logger":       true,
+		"_caller":       true,
+		"pid":           true,
+		"app_name":      true,
+		"exe":           true,
+	}
+
+	// omittableFields are the injected fields OmitFields accepts.
+	omittableFields = map[string]bool{
+		"pid": true,
+		"exe": true,
+	}
+
+	// buffers pools compressed messages.
+	buffers = sync.Pool{
+		New: func() interface{} { return new(bytes.Buffer) },
+	}
+
+	// newSnappyWriter creates snappy compressors, it is set when building with the snappy tag.
+	newSnappyWriter func(dst io.Writer) resetWriteCloser
+
+	// ErrTooManyChunks is returned by the GELF writer for UDP messages needing more than
+	// MaxChunkCount chunks, which Graylog would discard. They are counted as Stats.Dropped.
+	ErrTooManyChunks = errors.New("gelf message needs too many chunks")
+
+	// ErrMessageTooLarge is returned by the GELF writer for messages longer than MaxMessageBytes,
+	// which are dropped unless TruncateOversized is set.
+	ErrMessageTooLarge = errors.New("gelf message is too large")
+
+	errQueueFull      = errors.New("async queue is full")
+	errWriterClosed   = errors.New("graylog writer is closed")
+	errRedialCooldown = errors.New("graylog is unreachable, waiting before re-dialing")
 )
 
 // New creates new apilog.
-func New(configuration LoggingConfiguration) (*zap.Logger, error) {
+// The returned Logger holds the Graylog connection open, callers should
+// `defer log.Close()` in main so that the last entries are not lost on exit.
+func New(configuration LoggingConfiguration) (*Logger, error) {
+	if err := configuration.normalize(); err != nil {
+		return nil, err
+	}
+
+	var level zapcore.Level
+	if err := level.UnmarshalText([]byte(configuration.Level)); err != nil {
+		return nil, err
+	}
+
 	loggerConf := zap.NewProductionConfig()
+	loggerConf.Level = zap.NewAtomicLevelAt(level)
 	loggerConf.EncoderConfig = zapcore.EncoderConfig{
 		TimeKey:        "timestamp",
 		NameKey:        "_logger",
@@ -79,156 +561,1493 @@ func New(configuration LoggingConfiguration) (*zap.Logger, error) {
 		EncodeCaller:   zapcore.ShortCallerEncoder,
 		EncodeDuration: zapcore.SecondsDurationEncoder,
 	}
-	loggerConf.DisableStacktrace = true
-	loggerConf.DisableCaller = true
+	configuration.Keys.apply(&loggerConf.EncoderConfig)
+	loggerConf.DisableStacktrace = !configuration.EnableStacktrace
+	loggerConf.DisableCaller = !configuration.EnableCaller
 
-	var err error
+	// GELF requires the numeric timestamp, the console is read by humans
+	gelfEncoderConfig := loggerConf.EncoderConfig
+	loggerConf.EncoderConfig.EncodeTime = configuration.ConsoleTimeEncoder
 
-	corewrap := func(core zapcore.Core) zapcore.Core {
-		if configuration.GraylogAddress != "" {
-			var w = &writer{
-				chunkSize:        DefaultChunkSize,
-				chunkDataSize:    DefaultChunkSize - 12, // chunk size - chunk header size
-				compressionType:  CompressionGzip,
-				compressionLevel: gzip.BestCompression,
-			}
+	return build(loggerConf, gelfEncoderConfig, configuration)
+}
 
-			if w.conn, err = net.DialTimeout("udp", configuration.GraylogAddress, 15*time.Second); err != nil {
-				fmt.Println("could not connect with graylog, falling back to stdout")
-				return core
-			}
+// NewFromZapConfig creates a logger from a caller provided zap.Config, for control over the console
+// encoding, initial fields, sampling and hooks New doesn't expose, with the Graylog output of configuration.
+// The Graylog messages keep the keys GELF requires whatever the encoder config says: short_message,
+// level_name, the numeric timestamp and level, and the version, host and other fields New adds.
+// Initial fields must not use reserved keys, the level of configuration is ignored for the one of cfg,
+// and cfg.Sampling applies to Graylog too unless configuration.Sampling is set.
+func NewFromZapConfig(cfg zap.Config, configuration LoggingConfiguration) (*Logger, error) {
+	if err := configuration.normalize(); err != nil {
+		return nil, err
+	}
 
-			core = zapcore.NewCore(
-				zapcore.NewJSONEncoder(loggerConf.EncoderConfig),
-				zapcore.AddSync(w),
-				zap.NewAtomicLevel(),
-			)
+	var keys = make([]string, 0, len(cfg.InitialFields))
+	for key := range cfg.InitialFields {
+		if reservedFields[key] {
+			return nil, fmt.Errorf("initial field %q is reserved", key)
 		}
 
-		return core
+		keys = append(keys, key)
 	}
 
-	return loggerConf.Build(
-		zap.WrapCore(corewrap),
-		zap.Fields(
-			zap.Int("pid", os.Getpid()),
-			zap.String("app_name", configuration.AppName),
-			zap.String("host", configuration.Hostname),
-			zap.String("exe", path.Base(os.Args[0])),
-			zap.String("version", "1.1"), // GELF version
-		),
-	)
-}
+	sort.Strings(keys)
 
-// Close implementation of io.WriteCloser.
-func (*writeCloser) Close() error {
-	return nil
-}
+	// zap adds the initial fields to the console core only, add them after the Graylog core instead
+	var initialFields = make([]zap.Field, 0, len(keys))
+	for _, key := range keys {
+		initialFields = append(initialFields, zap.Any(key, cfg.InitialFields[key]))
+	}
 
-// Write implements io.Writer.
-func (w *writer) Write(buf []byte) (n int, err error) {
-	var (
-		cw   io.WriteCloser
-		cBuf bytes.Buffer
-	)
+	cfg.InitialFields = nil
 
-	switch w.compressionType {
-	case CompressionNone:
-		cw = &writeCloser{cBuf}
-	case CompressionGzip:
-		cw, err = gzip.NewWriterLevel(&cBuf, w.compressionLevel)
-	case CompressionZlib:
-		cw, err = zlib.NewWriterLevel(&cBuf, w.compressionLevel)
+	if cfg.Level == (zap.AtomicLevel{}) {
+		cfg.Level = zap.NewAtomicLevel()
 	}
 
-	if err != nil {
-		return 0, err
+	if configuration.Sampling == nil && cfg.Sampling != nil {
+		configuration.Sampling = cfg.Sampling
 	}
 
-	if n, err = cw.Write(buf); err != nil {
-		return n, err
+	gelfEncoderConfig := cfg.EncoderConfig
+	gelfEncoderConfig.MessageKey = "short_message"
+	gelfEncoderConfig.TimeKey = "timestamp"
+	gelfEncoderConfig.EncodeTime = zapcore.EpochTimeEncoder
+	gelfEncoderConfig.LevelKey = "level_name" // the numeric level is added by the GELF encoder
+	configuration.Keys.apply(&gelfEncoderConfig)
+
+	if gelfEncoderConfig.EncodeLevel == nil {
+		gelfEncoderConfig.EncodeLevel = zapcore.CapitalLevelEncoder
+	}
+
+	if gelfEncoderConfig.EncodeDuration == nil {
+		gelfEncoderConfig.EncodeDuration = zapcore.SecondsDurationEncoder
 	}
 
-	_ = cw.Close()
+	return build(cfg, gelfEncoderConfig, configuration, initialFields...)
+}
 
-	var cBytes = cBuf.Bytes()
-	if count := w.chunkCount(cBytes); count > 1 {
-		return w.writeChunked(count, cBytes)
+// build creates the logger from the console config and the Graylog encoder config,
+// fields are added after the GELF and static fields.
+func build(
+	loggerConf zap.Config,
+	gelfEncoderConfig zapcore.EncoderConfig,
+	configuration LoggingConfiguration,
+	fields ...zap.Field,
+) (*Logger, error) {
+	staticFields, err := buildStaticFields(configuration.StaticFields)
+	if err != nil {
+		return nil, err
 	}
 
-	if n, err = w.conn.Write(cBytes); err != nil {
-		return n, err
+	if configuration.Clock != nil {
+		gelfEncoderConfig.EncodeTime = clockTimeEncoder(configuration.Clock, gelfEncoderConfig.EncodeTime)
+		if loggerConf.EncoderConfig.EncodeTime != nil {
+			loggerConf.EncoderConfig.EncodeTime = clockTimeEncoder(configuration.Clock, loggerConf.EncoderConfig.EncodeTime)
+		}
 	}
 
-	if n != len(cBytes) {
-		return n, fmt.Errorf("writed %d bytes but should %d bytes", n, len(cBytes))
+	var sentry zapcore.Core
+	if configuration.Sentry != nil {
+		if sentry, err = newSentryCore(*configuration.Sentry); err != nil {
+			return nil, err
+		}
 	}
 
-	return n, nil
-}
+	var otel zapcore.Core
+	if configuration.OTel != nil {
+		if otel, err = NewOTelCore(*configuration.OTel); err != nil {
+			return nil, err
+		}
+	}
 
-// chunkCount calculate the number of GELF chunks.
-func (w *writer) chunkCount(b []byte) int {
-	lenB := len(b)
-	if lenB <= w.chunkSize {
-		return 1
+	sinks, err := newSinkCores(configuration.Sinks, newGELFEncoder(gelfEncoderConfig, configuration), loggerConf.Level)
+	if err != nil {
+		return nil, err
 	}
 
-	return len(b)/w.chunkDataSize + 1
-}
+	if configuration.FieldPrefix != "" {
+		for i, sink := range sinks {
+			sinks[i] = newPrefixCore(sink, configuration.FieldPrefix)
+		}
+	}
 
-// writeChunked send message by chunks.
-func (w *writer) writeChunked(count int, cBytes []byte) (n int, err error) {
-	if count > MaxChunkCount {
-		return 0, fmt.Errorf("need %d chunks but shold be later or equal to %d", count, MaxChunkCount)
+	// outputs are synced by Close even when the logger writes to stdout only
+	var outputs = append([]zapcore.Core(nil), sinks...)
+	for _, core := range []zapcore.Core{sentry, otel} {
+		if core != nil {
+			outputs = append(outputs, core)
+		}
 	}
 
 	var (
-		cBuf = bytes.NewBuffer(
-			make([]byte, 0, w.chunkSize),
-		)
-		nChunks   = uint8(count)
-		messageID = make([]byte, 8)
+		out     io.WriteCloser
+		w       *writer
+		async   *asyncWriter
+		replay  *replayWriter
+		dialErr error
 	)
 
-	if n, err = io.ReadFull(rand.Reader, messageID); err != nil || n != 8 {
-		return 0, fmt.Errorf("rand.Reader: %d/%s", n, err)
+	if configuration.GraylogAddress != "" {
+		if w, dialErr = newWriter(configuration); dialErr != nil {
+			if configuration.FailOnGraylogError {
+				return nil, dialErr
+			}
+
+			if configuration.FallbackBuffer > 0 {
+				replay = newReplayWriter(configuration)
+				out = replay
+
+				if configuration.Async {
+					async = newAsyncWriter(out, configuration.QueueSize, configuration.OverflowPolicy)
+					async.onDrop = replay.reportDrop
+					out = async
+				}
+			}
+		} else {
+			out, async = newOutput(w, configuration)
+		}
 	}
 
 	var (
-		off       int
-		chunkLen  int
-		bytesLeft = len(cBytes)
+		journal    *journal
+		journalErr error
 	)
 
-	for i := uint8(0); i < nChunks; i++ {
-		off = int(i) * w.chunkDataSize
-		chunkLen = w.chunkDataSize
-		if chunkLen > bytesLeft {
-			chunkLen = bytesLeft
+	if configuration.Journald {
+		journal, journalErr = newJournal()
+	}
+
+	redactKeys := configuration.redactKeys()
+	file := newFileSink(configuration)
+
+	// redact wraps each output core, so the tees keep checking their own levels
+	redact := func(core zapcore.Core) zapcore.Core {
+		if len(redactKeys) == 0 {
+			return core
 		}
 
-		cBuf.Reset()
-		cBuf.Write(chunkedMagicBytes)
-		cBuf.Write(messageID)
-		cBuf.WriteByte(i)
-		cBuf.WriteByte(nChunks)
-		cBuf.Write(cBytes[off : off+chunkLen])
+		return newRedactCore(core, redactKeys)
+	}
+
+	var dedup *dedupState
 
-		if n, err = w.conn.Write(cBuf.Bytes()); err != nil {
-			return len(cBytes) - bytesLeft + n, err
+	corewrap := func(core zapcore.Core) zapcore.Core {
+		core = redact(core)
+
+		var (
+			console  = core
+			fileCore zapcore.Core
+		)
+
+		if file != nil {
+			fileCore = zapcore.NewCore(newGELFEncoder(gelfEncoderConfig, configuration), zapcore.AddSync(file), loggerConf.Level)
+			if configuration.FieldPrefix != "" {
+				fileCore = newPrefixCore(fileCore, configuration.FieldPrefix)
+			}
+
+			if configuration.StructuredErrors {
+				fileCore = newErrorCore(fileCore)
+			}
+
+			fileCore = redact(fileCore)
+			core = zapcore.NewTee(fileCore, core)
 		}
 
-		if n != len(cBuf.Bytes()) {
-			n = len(cBytes) - bytesLeft + n
-			return n, fmt.Errorf("writed %d bytes but should %d bytes", n, len(cBytes))
+		if out != nil {
+			graylogCore := zapcore.NewCore(
+				newGELFEncoder(gelfEncoderConfig, configuration),
+				zapcore.AddSync(out),
+				loggerConf.Level,
+			)
+
+			if configuration.FieldPrefix != "" {
+				graylogCore = newPrefixCore(graylogCore, configuration.FieldPrefix)
+			}
+
+			if configuration.StructuredErrors {
+				graylogCore = newErrorCore(graylogCore)
+			}
+
+			graylogCore = redact(graylogCore)
+
+			if configuration.Sampling != nil {
+				graylogCore = newSamplerCore(graylogCore, *configuration.Sampling)
+			}
+
+			switch {
+			case configuration.MirrorToStdout:
+				core = zapcore.NewTee(graylogCore, core)
+			case replay != nil:
+				// the console is used until the collector is reached
+				core = zapcore.NewTee(graylogCore, newFallbackCore(console, replay))
+				if fileCore != nil {
+					core = zapcore.NewTee(core, fileCore)
+				}
+			case fileCore != nil:
+				core = zapcore.NewTee(graylogCore, fileCore)
+			default:
+				core = graylogCore
+			}
 		}
 
-		bytesLeft -= chunkLen
+		if journal != nil {
+			var journalCore = redact(newJournalCore(journal, loggerConf.Level))
+
+			switch {
+			case out != nil || configuration.MirrorToStdout:
+				core = zapcore.NewTee(core, journalCore)
+			case fileCore != nil:
+				core = zapcore.NewTee(fileCore, journalCore)
+			default:
+				core = journalCore
+			}
+		}
+
+		if sentry != nil {
+			core = zapcore.NewTee(core, redact(sentry))
+		}
+
+		if otel != nil {
+			core = zapcore.NewTee(core, redact(otel))
+		}
+
+		if len(sinks) > 0 {
+			var cores = []zapcore.Core{core}
+			for _, sink := range sinks {
+				cores = append(cores, redact(sink))
+			}
+
+			core = zapcore.NewTee(cores...)
+		}
+
+		if configuration.Dedup != nil {
+			core = newDedupCore(core, *configuration.Dedup)
+			dedup = core.(*dedupCore).state
+		}
+
+		if configuration.NthSampling != nil {
+			core = newNthSamplerCore(core, *configuration.NthSampling)
+		}
+
+		return core
 	}
 
-	if bytesLeft != 0 {
-		return len(cBytes) - bytesLeft, fmt.Errorf("error: %d bytes left after sending", bytesLeft)
+	log, err := loggerConf.Build(
+		zap.WrapCore(corewrap),
+		zap.AddCallerSkip(configuration.CallerSkip),
+		zap.Fields(gelfFields(configuration)...),
+		zap.Fields(staticFields...),
+		zap.Fields(fields...),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if configuration.AppName != "" {
+		log = log.Named(configuration.AppName)
+	}
+
+	if dialErr != nil {
+		log.Warn("falling back to stdout", zap.Error(dialErr))
+	}
+
+	if journalErr != nil {
+		log.Warn("falling back to stdout", zap.Error(journalErr))
+	}
+
+	var l = &Logger{Logger: log, level: loggerConf.Level, writer: out, gelf: w, async: async, replay: replay, outputs: outputs, dialErr: dialErr}
+	if file != nil {
+		l.file = file
+	}
+
+	if journal != nil {
+		l.journal = journal
+	}
+
+	if dedup != nil {
+		l.dedup = dedup
+	}
+
+	return l, nil
+}
+
+// clockTimeEncoder encodes the time of clock with encode, ignoring the entry time.
+func clockTimeEncoder(clock func() time.Time, encode zapcore.TimeEncoder) zapcore.TimeEncoder {
+	return func(_ time.Time, enc zapcore.PrimitiveArrayEncoder) {
+		encode(clock(), enc)
+	}
+}
+
+// gelfFields returns the fields attached to every message.
+func gelfFields(configuration LoggingConfiguration) []zap.Field {
+	var omit = make(map[string]bool, len(configuration.OmitFields))
+	for _, key := range configuration.OmitFields {
+		omit[key] = true
+	}
+
+	var fields = make([]zap.Field, 0, 6)
+	if !omit["pid"] {
+		fields = append(fields, zap.Int("pid", os.Getpid()))
+	}
+
+	fields = append(fields,
+		zap.String("app_name", configuration.AppName),
+		zap.String("host", configuration.Hostname),
+	)
+
+	if !omit["exe"] {
+		fields = append(fields, zap.String("exe", executableName(os.Args)))
+	}
+
+	fields = append(fields, zap.String("version", GELFVersion))
+
+	if configuration.Facility != "" {
+		fields = append(fields, zap.String(facilityKey, configuration.Facility))
+	}
+
+	if configuration.Kubernetes {
+		fields = append(fields, kubernetesFields()...)
+	}
+
+	return fields
+}
+
+// executableName returns the base name of the program from its arguments,
+// falling back to the running executable and then to "unknown",
+// as os.Args may be empty in embedded launch environments.
+func executableName(args []string) string {
+	if len(args) > 0 && args[0] != "" {
+		return filepath.Base(args[0])
+	}
+
+	if exe, err := os.Executable(); err == nil {
+		return filepath.Base(exe)
+	}
+
+	return "unknown"
+}
+
+// normalize validates the configuration and fills in the defaults.
+func (c *LoggingConfiguration) normalize() error {
+	if strings.HasPrefix(c.GraylogAddress, unixScheme) {
+		if c.Transport != "" && c.Transport != TransportUnix {
+			return fmt.Errorf("unix socket address requires %s transport but is %s", TransportUnix, c.Transport)
+		}
+
+		c.Transport = TransportUnix
+		c.GraylogAddress = strings.TrimPrefix(c.GraylogAddress, unixScheme)
+	}
+
+	if strings.HasPrefix(c.GraylogAddress, "http://") || strings.HasPrefix(c.GraylogAddress, "https://") {
+		if c.Transport != "" && c.Transport != TransportHTTP {
+			return fmt.Errorf("URL address requires %s transport but is %s", TransportHTTP, c.Transport)
+		}
+
+		c.Transport = TransportHTTP
+	}
+
+	switch {
+	case c.GraylogAddress == "" || c.Transport == TransportUnix:
+	case c.Transport == TransportKafka:
+		if c.KafkaProducer == nil {
+			return fmt.Errorf("%s transport requires KafkaProducer", TransportKafka)
+		}
+	case c.Transport == TransportHTTP:
+		if u, err := url.Parse(c.GraylogAddress); err != nil || u.Host == "" ||
+			(u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("GraylogAddress must be an http(s) URL but is %q", c.GraylogAddress)
+		}
+	default:
+		host, port, err := net.SplitHostPort(c.GraylogAddress)
+		if err != nil || host == "" || port == "" {
+			return fmt.Errorf("GraylogAddress must be host:port but is %q", c.GraylogAddress)
+		}
+	}
+
+	switch c.Transport {
+	case "":
+		c.Transport = TransportUDP
+		if c.TLSConfig != nil {
+			c.Transport = TransportTCP
+		}
+	case TransportUDP, TransportTCP, TransportUnix, TransportHTTP, TransportKafka:
+	default:
+		return fmt.Errorf("unknown transport %q", c.Transport)
+	}
+
+	// normalize a copy, the caller's configuration may share the slice
+	c.FailoverAddresses = append([]string(nil), c.FailoverAddresses...)
+	for i, address := range c.FailoverAddresses {
+		switch c.Transport {
+		case TransportHTTP, TransportKafka:
+			return fmt.Errorf("failover is not supported by the %s transport", c.Transport)
+		case TransportUnix:
+			c.FailoverAddresses[i] = strings.TrimPrefix(address, unixScheme)
+		default:
+			if host, port, err := net.SplitHostPort(address); err != nil || host == "" || port == "" {
+				return fmt.Errorf("failover address must be host:port but is %q", address)
+			}
+		}
+	}
+
+	if (c.Transport == TransportUDP || c.Transport == TransportKafka) && c.BatchSize > 0 {
+		return fmt.Errorf("batching is not supported by the %s transport", c.Transport)
+	}
+
+	if c.CallerFields && !c.EnableCaller {
+		return errors.New("CallerFields requires EnableCaller")
+	}
+
+	if c.CallerSkip < 0 {
+		return fmt.Errorf("invalid caller skip %d", c.CallerSkip)
+	}
+
+	if c.TLSConfig != nil && c.Transport != TransportTCP {
+		return fmt.Errorf("TLS requires %s transport but is %s", TransportTCP, c.Transport)
+	}
+
+	if c.RawGELF {
+		c.CompressionType, c.CompressionLevel = CompressionNone, 0
+	}
+
+	switch c.CompressionType {
+	case CompressionDefault:
+		c.CompressionType = CompressionGzip
+	case CompressionNone, CompressionGzip, CompressionZlib, CompressionZstd:
+	case CompressionSnappy:
+		if newSnappyWriter == nil {
+			return errors.New("snappy compression requires building with the snappy tag")
+		}
+	default:
+		return fmt.Errorf("unknown compression type %d", c.CompressionType)
+	}
+
+	minLevel, maxLevel, defaultLevel := compressionLevels(c.CompressionType)
+	if c.CompressionLevel == 0 {
+		c.CompressionLevel = defaultLevel
+	}
+
+	if c.CompressionType != CompressionNone &&
+		(c.CompressionLevel < minLevel || c.CompressionLevel > maxLevel) {
+		return fmt.Errorf("invalid compression level %d, the codec accepts %d to %d", c.CompressionLevel, minLevel, maxLevel)
+	}
+
+	if c.CompressionThreshold == 0 {
+		c.CompressionThreshold = DefaultCompressionThreshold
+	}
+
+	if c.ConsoleTimeEncoder == nil {
+		c.ConsoleTimeEncoder = zapcore.ISO8601TimeEncoder
+	}
+
+	if c.Hostname == "" {
+		var err error
+		if c.Hostname, err = os.Hostname(); err != nil || c.Hostname == "" {
+			c.Hostname = "unknown"
+		}
+	}
+
+	if c.QueueSize == 0 {
+		c.QueueSize = DefaultQueueSize
+	}
+
+	for _, key := range c.OmitFields {
+		if !omittableFields[key] {
+			return fmt.Errorf("field %q can't be omitted", key)
+		}
+	}
+
+	if err := c.Keys.validate(); err != nil {
+		return err
+	}
+
+	if c.FieldPrefix != "" && safeFieldName(c.FieldPrefix) != c.FieldPrefix {
+		return fmt.Errorf("field prefix %q isn't a valid GELF field name", c.FieldPrefix)
+	}
+
+	if c.WriteRetries == 0 {
+		c.WriteRetries = DefaultWriteRetries
+	}
+
+	if c.WriteRetries < 0 || c.Transport == TransportUDP {
+		c.WriteRetries = 1
+	}
+
+	if c.WriteTimeout == 0 && c.Transport != TransportUDP {
+		c.WriteTimeout = DefaultWriteTimeout
+	}
+
+	if c.WriteRetryDelay == 0 {
+		c.WriteRetryDelay = DefaultWriteRetryDelay
+	}
+
+	if c.DialTimeout == 0 {
+		c.DialTimeout = DefaultDialTimeout
+	}
+
+	if c.DialTimeout < 0 {
+		return fmt.Errorf("invalid dial timeout %s", c.DialTimeout)
+	}
+
+	if c.QueueSize < 0 {
+		return fmt.Errorf("invalid queue size %d", c.QueueSize)
+	}
+
+	if c.MaxMessageBytes < 0 {
+		return fmt.Errorf("invalid max message size %d", c.MaxMessageBytes)
+	}
+
+	if c.OverflowPolicy < OverflowDropNewest || c.OverflowPolicy > OverflowBlock {
+		return fmt.Errorf("unknown overflow policy %d", c.OverflowPolicy)
+	}
+
+	if c.FallbackBuffer < 0 || c.FallbackBufferBytes < 0 {
+		return fmt.Errorf("invalid fallback buffer of %d messages and %d bytes", c.FallbackBuffer, c.FallbackBufferBytes)
+	}
+
+	if c.FallbackBufferBytes == 0 {
+		c.FallbackBufferBytes = DefaultFallbackBufferBytes
+	}
+
+	if c.BatchSize < 0 {
+		return fmt.Errorf("invalid batch size %d", c.BatchSize)
+	}
+
+	if c.BatchInterval == 0 {
+		c.BatchInterval = DefaultBatchInterval
+	}
+
+	if c.Dedup != nil && (c.Dedup.Max < 0 || c.Dedup.Interval < 0) {
+		return fmt.Errorf("invalid dedup of %d entries per %s", c.Dedup.Max, c.Dedup.Interval)
+	}
+
+	if c.NthSampling != nil && c.NthSampling.Reset < 0 {
+		return fmt.Errorf("invalid nth sampling reset %s", c.NthSampling.Reset)
+	}
+
+	if c.FileMaxSize < 0 || c.FileMaxBackups < 0 || c.FileMaxAge < 0 {
+		return fmt.Errorf("invalid file rotation %d MB, %d backups, %d days", c.FileMaxSize, c.FileMaxBackups, c.FileMaxAge)
+	}
+
+	if c.ChunkSize == 0 {
+		c.ChunkSize = DefaultChunkSize
+	}
+
+	if c.ChunkSize <= chunkHeaderSize || c.ChunkSize > MaxChunkSize {
+		return fmt.Errorf("chunk size should be in (%d, %d] but is %d", chunkHeaderSize, MaxChunkSize, c.ChunkSize)
+	}
+
+	return nil
+}
+
+// compressionLevels returns the valid level range and the default level of the compression type.
+func compressionLevels(kind int) (min, max, def int) {
+	switch kind {
+	case CompressionZstd:
+		return int(zstd.SpeedFastest), int(zstd.SpeedBestCompression), int(zstd.SpeedDefault)
+	case CompressionSnappy:
+		return 0, 0, 0
+	}
+
+	return gzip.HuffmanOnly, gzip.BestCompression, gzip.BestCompression
+}
+
+// buildStaticFields converts static fields to zap fields sorted by key.
+func buildStaticFields(static map[string]string) ([]zap.Field, error) {
+	var keys = make([]string, 0, len(static))
+	for key := range static {
+		if reservedFields[key] {
+			return nil, fmt.Errorf("static field %q is reserved", key)
+		}
+
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var fields = make([]zap.Field, 0, len(keys))
+	for _, key := range keys {
+		fields = append(fields, zap.String(key, static[key]))
+	}
+
+	return fields, nil
+}
+
+// NewGELFWriter creates the Graylog writer used by New, with its transport, chunking and compression,
+// to send data not logged with zap to the same pipeline. Every Write sends p as one message,
+// so p must be a complete GELF JSON document. The writer is safe for concurrent use.
+// It returns a *GraylogError when Graylog is unreachable.
+// Async, FilePath and the zap related fields of the configuration are ignored.
+func NewGELFWriter(configuration LoggingConfiguration) (io.WriteCloser, error) {
+	if err := configuration.normalize(); err != nil {
+		return nil, err
+	}
+
+	if configuration.GraylogAddress == "" {
+		return nil, errors.New("GraylogAddress is required")
+	}
+
+	w, err := newWriter(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// NewWriteSyncer creates the Graylog output used by New, the writer of NewGELFWriter with the
+// batching and async queue of configuration, for zap cores built outside of New, e.g.
+//
+//	ws, err := logger.NewWriteSyncer(configuration)
+//	core := zapcore.NewCore(zapcore.NewJSONEncoder(gelfEncoderConfig), ws, zapcore.InfoLevel)
+//
+// The encoder must produce GELF documents. Sync waits for the queued messages and sends the pending
+// batch, it is a no-op for the plain writer which sends each message when it is written.
+// The returned WriteSyncer is an io.Closer closing the connection.
+func NewWriteSyncer(configuration LoggingConfiguration) (zapcore.WriteSyncer, error) {
+	if err := configuration.normalize(); err != nil {
+		return nil, err
+	}
+
+	if configuration.GraylogAddress == "" {
+		return nil, errors.New("GraylogAddress is required")
+	}
+
+	w, err := newWriter(configuration)
+	if err != nil {
+		return nil, err
+	}
+
+	out, _ := newOutput(w, configuration)
+
+	return &gelfWriteSyncer{WriteCloser: out}, nil
+}
+
+// gelfWriteSyncer syncs the batching and async writers.
+type gelfWriteSyncer struct {
+	io.WriteCloser
+}
+
+// Sync implements zapcore.WriteSyncer.
+func (s *gelfWriteSyncer) Sync() error {
+	return syncWriter(s.WriteCloser)
+}
+
+// newOutput wraps w with the batching and async writers of configuration,
+// async is nil unless configuration.Async is set.
+func newOutput(w *writer, configuration LoggingConfiguration) (out io.WriteCloser, async *asyncWriter) {
+	out = w
+	if configuration.BatchSize > 0 {
+		out = newBatchWriter(w, configuration.BatchSize, configuration.BatchInterval)
+	}
+
+	if configuration.Async {
+		async = newAsyncWriter(out, configuration.QueueSize, configuration.OverflowPolicy)
+		async.onDrop = w.reportDrop
+		out = async
+	}
+
+	return out, async
+}
+
+// newWriter creates the writer and connects it to Graylog.
+func newWriter(configuration LoggingConfiguration) (*writer, error) {
+	var w = &writer{
+		address:          configuration.GraylogAddress,
+		addresses:        append([]string{configuration.GraylogAddress}, configuration.FailoverAddresses...),
+		transport:        configuration.Transport,
+		tlsConfig:        configuration.TLSConfig,
+		httpClient:       configuration.HTTPClient,
+		kafka:            configuration.KafkaProducer,
+		chunkSize:        configuration.ChunkSize,
+		chunkDataSize:    configuration.ChunkSize - chunkHeaderSize,
+		compressionType:  configuration.CompressionType,
+		compressionLevel: configuration.CompressionLevel,
+		compressionMin:   configuration.CompressionThreshold,
+
+		truncateOversized: configuration.TruncateOversized,
+		maxMessageBytes:   configuration.MaxMessageBytes,
+		hostname:          configuration.Hostname,
+		hostnameFunc:      configuration.HostnameFunc,
+		retries:           configuration.WriteRetries,
+		retryDelay:        configuration.WriteRetryDelay,
+		dialTimeout:       configuration.DialTimeout,
+		writeTimeout:      configuration.WriteTimeout,
+
+		onError: configuration.OnError,
+		onDrop:  configuration.OnDrop,
+		onSend:  configuration.OnSend,
+	}
+
+	if w.stream() {
+		w.compressionType = CompressionNone
+	}
+
+	var seed [8]byte
+	if _, err := io.ReadFull(rand.Reader, seed[:]); err == nil {
+		w.idSeed = binary.BigEndian.Uint64(seed[:])
+	} else {
+		w.idSeed = uint64(time.Now().UnixNano())
+	}
+
+	if w.transport == TransportHTTP {
+		if w.httpClient == nil {
+			w.httpClient = newHTTPClient()
+		}
+
+		// the HTTP client manages its own connections
+		return w, nil
+	}
+
+	if w.transport == TransportKafka {
+		// the producer connects to the brokers
+		return w, nil
+	}
+
+	var err error
+	for i, address := range w.addresses {
+		conn, dErr := w.dialAddress(address)
+		if dErr == nil {
+			w.switchTo(conn, i)
+			return w, nil
+		}
+
+		if err == nil {
+			err = dErr
+		}
+	}
+
+	return nil, &GraylogError{Address: w.address, Err: err}
+}
+
+// Error implements error.
+func (e *GraylogError) Error() string {
+	return fmt.Sprintf("could not connect with graylog at %s: %s", e.Address, e.Err)
+}
+
+// Unwrap returns the dial error.
+func (e *GraylogError) Unwrap() error {
+	return e.Err
+}
+
+// AtomicLevel returns the level shared by all the logger outputs,
+// so it can be changed at runtime, e.g. from an HTTP endpoint.
+func (l *Logger) AtomicLevel() zap.AtomicLevel {
+	return l.level
+}
+
+// WithFacility returns a child logger whose messages have the GELF facility name,
+// e.g. per subsystem. Graylog messages have a single facility field, overriding the configured one,
+// the same holds for zap.String("facility", name) on any logger.
+func (l *Logger) WithFacility(name string) *zap.Logger {
+	return l.With(zap.String(facilityKey, name))
+}
+
+// Component returns the child logger with the component field set to name, created once per name
+// and reused afterwards, e.g. log.Component("db") in each request handler. The children are kept
+// for the lifetime of the logger: use it for a small fixed set of names, not per-request values.
+func (l *Logger) Component(name string) *zap.Logger {
+	if child, ok := l.components.Load(name); ok {
+		return child.(*zap.Logger)
+	}
+
+	child, _ := l.components.LoadOrStore(name, l.With(zap.String("component", name)))
+
+	return child.(*zap.Logger)
+}
+
+// Named returns a child logger of base whose name, the GELF _logger field, is appended with name,
+// e.g. "api.db" for Named(log, "db") when AppName is "api", so components can be filtered in Graylog.
+func Named(base *zap.Logger, name string) *zap.Logger {
+	return base.Named(name)
+}
+
+// Dropped returns how many messages the async queue dropped because it was full.
+func (l *Logger) Dropped() uint64 {
+	if l.async == nil {
+		return 0
+	}
+
+	return l.async.Dropped()
+}
+
+// Stats returns the Graylog output counters, they are zero when the logger fell back to stdout.
+func (l *Logger) Stats() Stats {
+	var stats Stats
+	switch {
+	case l.gelf != nil:
+		stats = l.gelf.Stats()
+	case l.replay != nil:
+		stats = l.replay.Stats()
+	}
+
+	stats.Dropped += l.Dropped()
+
+	return stats
+}
+
+// Degraded reports whether the logger fell back to stdout because Graylog was unreachable at startup,
+// e.g. for monitoring. It becomes false once the FallbackBuffer writer connects in the background.
+func (l *Logger) Degraded() bool {
+	return l.DegradedReason() != nil
+}
+
+// DegradedReason returns the *GraylogError of the fallback while the logger is Degraded, nil otherwise.
+func (l *Logger) DegradedReason() error {
+	if l.dialErr == nil || l.replay != nil && l.replay.Connected() {
+		return nil
+	}
+
+	return l.dialErr
+}
+
+// Flush writes the buffered entries without closing the logger, e.g. at checkpoints
+// or before handling SIGHUP: it waits for the entries queued by Async and sends the pending batch,
+// then syncs the other outputs like Sync, which does the same. It is safe to call repeatedly.
+func (l *Logger) Flush() error {
+	return l.Sync()
+}
+
+// Close flushes buffered entries, waits for the async queue to drain
+// and closes the Graylog connection and the file.
+// When the logger fell back to stdout, it only writes the pending dedup summaries and flushes
+// the Sentry, OTel and sink outputs, since syncing stdout fails on terminals and pipes.
+func (l *Logger) Close() error {
+	var err error
+	if l.dedup != nil {
+		err = l.dedup.Close()
+	}
+
+	if l.writer == nil && l.file == nil && l.journal == nil {
+		for _, core := range l.outputs {
+			if sErr := core.Sync(); err == nil {
+				err = sErr
+			}
+		}
+
+		return err
+	}
+
+	if sErr := l.Sync(); err == nil {
+		err = sErr
+	}
+
+	if l.writer != nil {
+		if cErr := l.writer.Close(); err == nil {
+			err = cErr
+		}
+	}
+
+	if l.file != nil {
+		if cErr := l.file.Close(); err == nil {
+			err = cErr
+		}
+	}
+
+	if l.journal != nil {
+		if cErr := l.journal.Close(); err == nil {
+			err = cErr
+		}
+	}
+
+	return err
+}
+
+// Shutdown closes the logger like Close, giving up when ctx is done before the buffered entries
+// are written, e.g. when the collector is wedged. It then closes the Graylog connection, so the
+// blocked writes fail, and drops the entries queued by Async, so Close finishes in the background,
+// and returns ctx.Err().
+func (l *Logger) Shutdown(ctx context.Context) error {
+	var closed = make(chan error, 1)
+	go func() {
+		closed <- l.Close()
+	}()
+
+	select {
+	case err := <-closed:
+		return err
+	case <-ctx.Done():
+		l.abort()
+		return ctx.Err()
+	}
+}
+
+// abort discards the entries queued by Async and closes the Graylog connection
+// of the writer or the replay writer, so the writes blocking Close fail.
+func (l *Logger) abort() {
+	if l.async != nil {
+		l.async.abort()
+	}
+
+	if l.gelf != nil {
+		_ = l.gelf.Close()
+	}
+
+	if l.replay != nil {
+		l.replay.abort()
+	}
+}
+
+// stream reports whether the transport is a stream, framed by null bytes instead of chunks.
+func (w *writer) stream() bool {
+	return w.transport != TransportUDP
+}
+
+// dial opens a new connection to Graylog.
+// The dial timeout includes the TLS handshake. TCP dials try the resolved addresses until one
+// connects, racing IPv4 against IPv6 (happy eyeballs), UDP dials try them until a socket can be
+// connected, e.g. skipping IPv6 addresses without a route.
+func (w *writer) dial() (net.Conn, error) {
+	return w.dialAddress(w.address)
+}
+
+// dialAddress opens a new connection to the collector at address, see dial.
+func (w *writer) dialAddress(address string) (net.Conn, error) {
+	var dialer = &net.Dialer{Timeout: w.dialTimeout}
+	if w.tlsConfig != nil {
+		return tls.DialWithDialer(dialer, w.transport, address, w.tlsConfig)
+	}
+
+	if w.transport == TransportUDP {
+		return dialUDP(dialer, address)
+	}
+
+	return dialer.Dial(w.transport, address)
+}
+
+// failbackInterval is how often a failed over writer retries the primary collector, replaced in tests.
+var failbackInterval = 30 * time.Second
+
+// lookupIPAddr resolves host names, replaced in tests.
+var lookupIPAddr = net.DefaultResolver.LookupIPAddr
+
+// dialUDP connects a UDP socket to the first resolved address of address that accepts it,
+// in the resolver order which prefers reachable address families.
+func dialUDP(dialer *net.Dialer, address string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, err
+	}
+
+	if net.ParseIP(host) != nil {
+		return dialer.Dial(TransportUDP, address)
+	}
+
+	var ctx = context.Background()
+	if dialer.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, dialer.Timeout)
+		defer cancel()
+	}
+
+	addrs, err := lookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, addr := range addrs {
+		var conn net.Conn
+		if conn, err = dialer.Dial(TransportUDP, net.JoinHostPort(addr.String(), port)); err == nil {
+			return conn, nil
+		}
+	}
+
+	if err == nil {
+		err = fmt.Errorf("no addresses for %s", host)
+	}
+
+	return nil, err
+}
+
+// connection returns the current connection.
+// It starts a failback to the primary collector when it is due.
+func (w *writer) connection() net.Conn {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.current != 0 && !w.probing && !w.closed && time.Now().After(w.failbackAt) {
+		w.probing = true
+		go w.failback()
+	}
+
+	return w.conn
+}
+
+// failback replaces the failed over connection by one to the primary collector when it connects.
+func (w *writer) failback() {
+	conn, err := w.dialAddress(w.addresses[0])
+
+	// the connection isn't replaced in the middle of a message
+	w.writeMu.Lock()
+	defer w.writeMu.Unlock()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.probing = false
+	w.failbackAt = time.Now().Add(failbackInterval)
+
+	if err != nil {
+		return
+	}
+
+	if w.closed || w.current == 0 {
+		_ = conn.Close()
+		return
+	}
+
+	_ = w.conn.Close()
+	w.switchTo(conn, 0)
+}
+
+// switchTo sets the connection to the collector of index, w.mu must be held once the writer is created.
+func (w *writer) switchTo(conn net.Conn, index int) {
+	w.conn = conn
+	w.current = index
+	w.address = w.addresses[index]
+	w.failbackAt = time.Now().Add(failbackInterval)
+}
+
+// reconnect replaces the failed connection, retrying a few times with a short backoff.
+// Each attempt dials the other collectors in failover order before the failed one.
+// After the retries are exhausted it refuses to dial again for redialCooldown,
+// so a down collector doesn't stall every log call.
+func (w *writer) reconnect(failed net.Conn) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errWriterClosed
+	}
+
+	if w.conn != failed {
+		// already replaced by a concurrent write
+		return nil
+	}
+
+	if time.Now().Before(w.redialAt) {
+		return errRedialCooldown
+	}
+
+	var addresses = w.addresses
+	if len(addresses) == 0 {
+		addresses = []string{w.address}
+	}
+
+	var err error
+	for i := 0; i < redialAttempts; i++ {
+		if i > 0 {
+			time.Sleep(time.Duration(i) * redialBackoff)
+		}
+
+		for j := 1; j <= len(addresses); j++ {
+			var (
+				next = (w.current + j) % len(addresses)
+				conn net.Conn
+			)
+
+			if conn, err = w.dialAddress(addresses[next]); err == nil {
+				_ = w.conn.Close()
+				w.conn, w.current, w.address = conn, next, addresses[next]
+				w.failbackAt = time.Now().Add(failbackInterval)
+
+				return nil
+			}
+		}
+	}
+
+	w.redialAt = time.Now().Add(redialCooldown)
+
+	return err
+}
+
+// Close closes the Graylog connection.
+func (w *writer) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+
+	if w.kafka != nil {
+		return w.kafka.Close()
+	}
+
+	if w.conn == nil {
+		return nil
+	}
+
+	return w.conn.Close()
+}
+
+// Write implements io.Writer.
+func (w *writer) Write(buf []byte) (n int, err error) {
+	if buf, err = w.limitSize(buf); err != nil {
+		return 0, err
+	}
+
+	var cBuf = buffers.Get().(*bytes.Buffer)
+	defer buffers.Put(cBuf)
+
+	payload, err := w.encode(cBuf, buf)
+	if err != nil {
+		atomic.AddUint64(&w.stats.WriteErrors, 1)
+		w.report(EventWriteError, err)
+
+		return 0, err
+	}
+
+	if w.truncateOversized && !w.stream() && w.chunkCount(payload) > MaxChunkCount {
+		// truncating the uncompressed message to what fits uncompressed is conservative,
+		// but doesn't require guessing the compression ratio
+		var size = len(buf)
+		if buf, err = truncateMessage(buf, MaxChunkCount*w.chunkDataSize); err == nil {
+			payload, err = w.encode(cBuf, buf)
+		}
+
+		if err != nil {
+			atomic.AddUint64(&w.stats.Dropped, 1)
+			w.reportDrop(err)
+
+			return 0, err
+		}
+
+		w.report(EventTruncated, fmt.Errorf("%w: message of %d bytes truncated to %d bytes", ErrTooManyChunks, size, len(buf)))
+	}
+
+	if n, err = w.send(payload); err != nil {
+		if errors.Is(err, ErrTooManyChunks) {
+			atomic.AddUint64(&w.stats.Dropped, 1)
+			w.reportDrop(err)
+		} else {
+			atomic.AddUint64(&w.stats.WriteErrors, 1)
+			w.report(EventWriteError, err)
+		}
+
+		return n, err
+	}
+
+	atomic.AddUint64(&w.stats.Sent, 1)
+
+	return n, nil
+}
+
+// limitSize returns buf, truncated when it is longer than MaxMessageBytes and TruncateOversized
+// is set, or ErrMessageTooLarge for the dropped message otherwise.
+func (w *writer) limitSize(buf []byte) ([]byte, error) {
+	if w.maxMessageBytes <= 0 || len(buf) <= w.maxMessageBytes {
+		return buf, nil
+	}
+
+	var (
+		size = len(buf)
+		err  error
+	)
+
+	if !w.truncateOversized {
+		err = fmt.Errorf("%w: %d bytes exceed %d", ErrMessageTooLarge, size, w.maxMessageBytes)
+	} else if buf, err = truncateMessage(buf, w.maxMessageBytes); err == nil {
+		w.report(EventTruncated, fmt.Errorf("%w: message of %d bytes truncated to %d bytes", ErrMessageTooLarge, size, len(buf)))
+	}
+
+	if err != nil {
+		atomic.AddUint64(&w.stats.Dropped, 1)
+		w.reportDrop(err)
+
+		return nil, err
+	}
+
+	return buf, nil
+}
+
+// report calls the OnError callback.
+func (w *writer) report(event string, err error) {
+	if w.onError != nil {
+		w.onError(event, err)
+	}
+}
+
+// reportDrop calls the OnDrop and OnError callbacks for a message dropped because of err.
+func (w *writer) reportDrop(err error) {
+	if w.onDrop != nil {
+		w.onDrop(1)
+	}
+
+	w.report(EventDropped, err)
+}
+
+// Stats returns the writer counters.
+func (w *writer) Stats() Stats {
+	return Stats{
+		Sent:        atomic.LoadUint64(&w.stats.Sent),
+		Dropped:     atomic.LoadUint64(&w.stats.Dropped),
+		WriteErrors: atomic.LoadUint64(&w.stats.WriteErrors),
+	}
+}
+
+// encode returns the payload of buf, compressed to cBuf. Uncompressed UDP messages are sent
+// from buf directly, without copying, streams copy them to cBuf to append the frame delimiter.
+func (w *writer) encode(cBuf *bytes.Buffer, buf []byte) ([]byte, error) {
+	if w.compressionType == CompressionNone && !w.stream() {
+		return buf, nil
+	}
+
+	cBuf.Reset()
+	if err := w.compress(cBuf, buf); err != nil {
+		return nil, err
+	}
+
+	return cBuf.Bytes(), nil
+}
+
+// compress writes buf into dst with the writer compression,
+// messages shorter than the compression threshold are written as is.
+func (w *writer) compress(dst *bytes.Buffer, buf []byte) error {
+	if w.compressionType == CompressionNone || len(buf) < w.compressionMin {
+		_, err := dst.Write(buf)
+		return err
+	}
+
+	cw, err := w.compressor(dst)
+	if err != nil {
+		return err
+	}
+	defer w.compressors.Put(cw)
+
+	if _, err = cw.Write(buf); err != nil {
+		return err
+	}
+
+	// Close flushes the compressor, so it must happen before reading the buffer
+	// and leaves nothing pending for the next user of the pooled compressor.
+	return cw.Close()
+}
+
+// compressor takes a compressor from the pool and resets it to dst,
+// creating a new one when the pool is empty.
+func (w *writer) compressor(dst io.Writer) (resetWriteCloser, error) {
+	if cw, ok := w.compressors.Get().(resetWriteCloser); ok {
+		cw.Reset(dst)
+		return cw, nil
+	}
+
+	var (
+		cw  resetWriteCloser
+		err error
+	)
+
+	switch w.compressionType {
+	case CompressionGzip:
+		cw, err = gzip.NewWriterLevel(dst, w.compressionLevel)
+	case CompressionZlib:
+		cw, err = zlib.NewWriterLevel(dst, w.compressionLevel)
+	case CompressionZstd:
+		cw, err = zstd.NewWriter(dst,
+			zstd.WithEncoderLevel(zstd.EncoderLevel(w.compressionLevel)),
+			zstd.WithEncoderConcurrency(1),
+		)
+	case CompressionSnappy:
+		cw = newSnappyWriter(dst)
+	default:
+		err = fmt.Errorf("unknown compression type %d", w.compressionType)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return cw, nil
+}
+
+// send writes the message, re-dialing and retrying on network errors.
+// UDP writes are retried once, stream writes up to the configured retries with backoff.
+func (w *writer) send(cBytes []byte) (n int, err error) {
+	for attempt := 0; ; attempt++ {
+		var conn net.Conn
+		if w.transport == TransportHTTP {
+			n, err = w.post(cBytes)
+		} else if w.transport == TransportKafka {
+			n, err = w.produce(cBytes)
+		} else {
+			conn = w.connection()
+
+			w.writeMu.Lock()
+			n, err = w.sendTo(conn, cBytes)
+			w.writeMu.Unlock()
+		}
+
+		if err == nil {
+			return n, nil
+		}
+
+		if _, ok := err.(net.Error); !ok || attempt == w.retries {
+			return n, err
+		}
+
+		if attempt > 0 {
+			time.Sleep(w.backoff(attempt))
+		}
+
+		if conn != nil {
+			w.report(EventReconnect, err)
+
+			if w.reconnect(conn) != nil {
+				return n, err
+			}
+		}
+	}
+}
+
+// backoff returns the delay before the given retry, doubling the retry delay
+// for each attempt up to redialCooldown and picking a random point in the upper half
+// against thundering herds.
+func (w *writer) backoff(attempt int) time.Duration {
+	var delay = w.retryDelay
+	for i := 1; i < attempt && delay < redialCooldown; i++ {
+		delay *= 2
+	}
+
+	if delay > redialCooldown {
+		delay = redialCooldown
+	}
+
+	return delay/2 + time.Duration(mathrand.Int63n(int64(delay/2)+1))
+}
+
+// sendTo writes the message framed for the writer transport.
+func (w *writer) sendTo(conn net.Conn, cBytes []byte) (n int, err error) {
+	if w.stream() {
+		return w.writeStream(conn, cBytes)
+	}
+
+	var count = w.chunkCount(cBytes)
+	if count > MaxChunkCount {
+		return 0, tooManyChunks(len(cBytes), count, w.chunkSize)
+	}
+
+	if count > 1 {
+		n, err = w.writeChunked(conn, count, w.chunkDataSize, cBytes)
+	} else if n, err = conn.Write(cBytes); err == nil && n != len(cBytes) {
+		err = fmt.Errorf("wrote %d of %d bytes", n, len(cBytes))
+	}
+
+	if n == 0 && w.chunkSize > MTUChunkSize && messageTooLong(err) {
+		// the datagrams exceed the path MTU, send the message in smaller chunks instead,
+		// even when it fits a single datagram of chunkSize
+		const dataSize = MTUChunkSize - chunkHeaderSize
+		if count = (len(cBytes) + dataSize - 1) / dataSize; count > MaxChunkCount {
+			return 0, tooManyChunks(len(cBytes), count, MTUChunkSize)
+		}
+
+		return w.writeChunked(conn, count, dataSize, cBytes)
+	}
+
+	return n, err
+}
+
+// tooManyChunks returns the ErrTooManyChunks error of a size bytes message needing count chunks.
+func tooManyChunks(size, count, chunkSize int) error {
+	return fmt.Errorf("%w: %d bytes need %d chunks of %d bytes but at most %d are allowed, "+
+		"use a larger ChunkSize, compression or TruncateOversized", ErrTooManyChunks, size, count, chunkSize, MaxChunkCount)
+}
+
+// messageTooLong reports whether err is EMSGSIZE, returned instead of a short write
+// for UDP datagrams larger than the path MTU.
+func messageTooLong(err error) bool {
+	return errors.Is(err, syscall.EMSGSIZE)
+}
+
+// writeStream send null byte terminated message.
+func (w *writer) writeStream(conn net.Conn, cBytes []byte) (n int, err error) {
+	var frame = append(cBytes, 0x00)
+
+	if w.writeTimeout > 0 {
+		if err = conn.SetWriteDeadline(time.Now().Add(w.writeTimeout)); err != nil {
+			return 0, err
+		}
+	}
+
+	if n, err = conn.Write(frame); err != nil {
+		return n, err
+	}
+
+	if n != len(frame) {
+		return n, fmt.Errorf("wrote %d of %d bytes", n, len(frame))
+	}
+
+	return len(cBytes), nil
+}
+
+// chunkCount calculate the number of GELF chunks.
+func (w *writer) chunkCount(b []byte) int {
+	lenB := len(b)
+	if lenB <= w.chunkSize {
+		return 1
+	}
+
+	return (lenB + w.chunkDataSize - 1) / w.chunkDataSize
+}
+
+// messageID returns a new chunked message id.
+// The counter makes ids unique for the writer, the random seed across senders.
+// Multiplying by an odd constant spreads consecutive counters over all the bits.
+func (w *writer) messageID() (id [8]byte) {
+	binary.BigEndian.PutUint64(id[:], w.idSeed^(atomic.AddUint64(&w.idCounter, 1)*0x9e3779b97f4a7c15))
+	return id
+}
+
+// buildChunk appends to dst the GELF chunk of data, the sequence number index of total chunks
+// of the message: the magic bytes 0x1e 0x0f, the 8 bytes message id, index and total
+// make the chunkHeaderSize bytes header.
+func buildChunk(dst []byte, messageID [8]byte, index, total uint8, data []byte) []byte {
+	dst = append(dst, chunkedMagicBytes...)
+	dst = append(dst, messageID[:]...)
+	dst = append(dst, index, total)
+
+	return append(dst, data...)
+}
+
+// writeChunked send message by count chunks of dataSize bytes, count is at most MaxChunkCount.
+func (w *writer) writeChunked(conn net.Conn, count, dataSize int, cBytes []byte) (n int, err error) {
+	var (
+		chunk     = make([]byte, 0, dataSize+chunkHeaderSize)
+		nChunks   = uint8(count)
+		messageID = w.messageID()
+		off       int
+		chunkLen  int
+		bytesLeft = len(cBytes)
+	)
+
+	for i := uint8(0); i < nChunks; i++ {
+		off = int(i) * dataSize
+		chunkLen = dataSize
+		if chunkLen > bytesLeft {
+			chunkLen = bytesLeft
+		}
+
+		chunk = buildChunk(chunk[:0], messageID, i, nChunks, cBytes[off:off+chunkLen])
+
+		if n, err = conn.Write(chunk); err != nil {
+			return len(cBytes) - bytesLeft + n, err
+		}
+
+		if n != len(chunk) {
+			n = len(cBytes) - bytesLeft + n
+			return n, fmt.Errorf("wrote %d of %d bytes", n, len(cBytes))
+		}
+
+		bytesLeft -= chunkLen
+	}
+
+	if bytesLeft != 0 {
+		return len(cBytes) - bytesLeft, fmt.Errorf("error: %d bytes left after sending", bytesLeft)
+	}
+
+	if w.onSend != nil {
+		// copied so messageID stays on the stack without a callback
+		var id = messageID
+		w.onSend(id[:], count)
 	}
 
 	return len(cBytes), nil
@@ -0,0 +1,682 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func TestWriterChunkSize(t *testing.T) {
+	ln, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer ln.Close()
+
+	const chunkSize = 512
+
+	w := &writer{
+		transport:       TransportUDP,
+		chunkSize:       chunkSize,
+		chunkDataSize:   chunkSize - chunkHeaderSize,
+		compressionType: CompressionNone,
+	}
+
+	if w.conn, err = net.Dial("udp", ln.LocalAddr().String()); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer w.Close()
+
+	payload := bytes.Repeat([]byte("x"), 3*chunkSize)
+
+	count := w.chunkCount(payload)
+	if count != 4 {
+		t.Fatalf("expected 4 chunks but got %d", count)
+	}
+
+	if _, err = w.Write(payload); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	buf := make([]byte, MaxChunkSize)
+	for i := 0; i < count; i++ {
+		_ = ln.SetReadDeadline(time.Now().Add(time.Second))
+
+		n, _, err := ln.ReadFrom(buf)
+		if err != nil {
+			t.Fatalf("chunk %d: %s", i, err)
+		}
+
+		if n > chunkSize {
+			t.Fatalf("chunk %d is %d bytes, larger than %d", i, n, chunkSize)
+		}
+
+		if buf[10] != byte(i) || buf[11] != byte(count) {
+			t.Fatalf("chunk %d has sequence %d/%d", i, buf[10], buf[11])
+		}
+	}
+}
+
+func TestWriterReconnect(t *testing.T) {
+	ln, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	addr := ln.LocalAddr().String()
+	w := &writer{
+		address:         addr,
+		transport:       TransportUDP,
+		chunkSize:       DefaultChunkSize,
+		chunkDataSize:   DefaultChunkSize - chunkHeaderSize,
+		compressionType: CompressionNone,
+		retries:         1,
+	}
+
+	if w.conn, err = w.dial(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer w.Close()
+
+	buf := make([]byte, MaxChunkSize)
+	read := func(ln net.PacketConn) string {
+		_ = ln.SetReadDeadline(time.Now().Add(time.Second))
+
+		n, _, err := ln.ReadFrom(buf)
+		if err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		return string(buf[:n])
+	}
+
+	if _, err = w.Write([]byte("one")); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if msg := read(ln); msg != "one" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+
+	_ = ln.Close()
+	conn := w.connection()
+
+	// the first datagram to a closed port triggers ICMP unreachable,
+	// which fails the next write on the same socket
+	_, _ = w.Write([]byte("lost"))
+	time.Sleep(50 * time.Millisecond)
+
+	if _, err = w.Write([]byte("lost")); err != nil {
+		t.Fatal("write is not recovered:", err)
+	}
+
+	if w.connection() == conn {
+		t.Fatal("connection is not replaced")
+	}
+
+	if ln, err = net.ListenPacket("udp", addr); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer ln.Close()
+
+	if _, err = w.Write([]byte("two")); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if msg := read(ln); msg != "two" {
+		t.Fatalf("unexpected message %q", msg)
+	}
+}
+
+func TestWriterWriteTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer ln.Close()
+
+	// the collector accepts connections but never reads them
+	var (
+		mu    sync.Mutex
+		conns []net.Conn
+	)
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			mu.Lock()
+			conns = append(conns, conn)
+			mu.Unlock()
+		}
+	}()
+
+	defer func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		for _, conn := range conns {
+			_ = conn.Close()
+		}
+	}()
+
+	var (
+		ev            = &events{}
+		configuration = LoggingConfiguration{
+			GraylogAddress: ln.Addr().String(),
+			Transport:      TransportTCP,
+			WriteTimeout:   50 * time.Millisecond,
+			WriteRetries:   -1,
+			OnError:        ev.onError,
+		}
+	)
+
+	if err = configuration.normalize(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	w, err := newWriter(configuration)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer w.Close()
+
+	var msg = bytes.Repeat([]byte("x"), 1<<20)
+	for i := 0; i < 100 && len(ev.errors[EventReconnect]) == 0; i++ {
+		_, _ = w.Write(msg)
+	}
+
+	ev.mu.Lock()
+	defer ev.mu.Unlock()
+
+	var netErr net.Error
+	if reconnects := ev.errors[EventReconnect]; len(reconnects) == 0 || !errors.As(reconnects[0], &netErr) || !netErr.Timeout() {
+		t.Fatalf("expected reconnect after a write timeout but got %v", ev.errors)
+	}
+}
+
+func TestWriterZstd(t *testing.T) {
+	ln, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer ln.Close()
+
+	w := &writer{
+		address:          ln.LocalAddr().String(),
+		transport:        TransportUDP,
+		chunkSize:        DefaultChunkSize,
+		chunkDataSize:    DefaultChunkSize - chunkHeaderSize,
+		compressionType:  CompressionZstd,
+		compressionLevel: int(zstd.SpeedDefault),
+	}
+
+	if w.conn, err = w.dial(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer w.Close()
+
+	dec, err := zstd.NewReader(nil)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer dec.Close()
+
+	frame := []byte(`{"version":"1.1","host":"localhost","short_message":"zstd","timestamp":1.5,"level_name":"INFO"}`)
+	buf := make([]byte, MaxChunkSize)
+
+	// the second write reuses the pooled encoder
+	for i := 0; i < 2; i++ {
+		if _, err = w.Write(frame); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		_ = ln.SetReadDeadline(time.Now().Add(time.Second))
+
+		n, _, err := ln.ReadFrom(buf)
+		if err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		decoded, err := dec.DecodeAll(buf[:n], nil)
+		if err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		if !bytes.Equal(decoded, frame) {
+			t.Fatalf("unexpected frame %q", decoded)
+		}
+	}
+}
+
+func BenchmarkWriterWrite(b *testing.B) {
+	ln, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal("error occurred:", err)
+	}
+	defer ln.Close()
+
+	frame := []byte(`{"version":"1.1","host":"localhost","short_message":"benchmark","timestamp":1.5,"level_name":"INFO","app_name":"test","pid":42}`)
+
+	for _, bench := range []struct {
+		name      string
+		kind      int
+		level     int
+		threshold int
+	}{
+		{"none", CompressionNone, 0, 0},
+		{"gzip", CompressionGzip, 6, 0},
+		{"gzip-threshold", CompressionGzip, 6, DefaultCompressionThreshold},
+		{"zlib", CompressionZlib, 6, 0},
+		{"zstd", CompressionZstd, int(zstd.SpeedDefault), 0},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			w := &writer{
+				address:          ln.LocalAddr().String(),
+				transport:        TransportUDP,
+				chunkSize:        DefaultChunkSize,
+				chunkDataSize:    DefaultChunkSize - chunkHeaderSize,
+				compressionType:  bench.kind,
+				compressionLevel: bench.level,
+				compressionMin:   bench.threshold,
+			}
+
+			if w.conn, err = w.dial(); err != nil {
+				b.Fatal("error occurred:", err)
+			}
+			defer w.Close()
+
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err = w.Write(frame); err != nil {
+					b.Fatal("error occurred:", err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkWriterWriteChunked(b *testing.B) {
+	ln, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		b.Fatal("error occurred:", err)
+	}
+	defer ln.Close()
+
+	w := &writer{address: ln.LocalAddr().String(), transport: TransportUDP}
+	if w.conn, err = w.dial(); err != nil {
+		b.Fatal("error occurred:", err)
+	}
+	defer w.Close()
+
+	var (
+		dataSize = MTUChunkSize - chunkHeaderSize
+		msg      = make([]byte, 16*dataSize)
+	)
+
+	b.SetBytes(int64(len(msg)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err = w.writeChunked(w.conn, 16, dataSize, msg); err != nil {
+			b.Fatal("error occurred:", err)
+		}
+	}
+}
+
+func TestWriterCompressorPool(t *testing.T) {
+	for _, kind := range []int{CompressionGzip, CompressionZlib} {
+		w := &writer{compressionType: kind, compressionLevel: gzip.BestSpeed}
+
+		// the second message reuses the pooled compressor
+		for _, msg := range []string{`{"short_message":"first"}`, `{"short_message":"second"}`} {
+			var buf bytes.Buffer
+			if err := w.compress(&buf, []byte(msg)); err != nil {
+				t.Fatal("error occurred:", err)
+			}
+
+			var (
+				r   io.Reader
+				err error
+			)
+
+			if kind == CompressionGzip {
+				r, err = gzip.NewReader(&buf)
+			} else {
+				r, err = zlib.NewReader(&buf)
+			}
+
+			if err != nil {
+				t.Fatal("error occurred:", err)
+			}
+
+			decoded, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatal("error occurred:", err)
+			}
+
+			if string(decoded) != msg {
+				t.Fatalf("unexpected message %q", decoded)
+			}
+		}
+	}
+}
+
+func TestWriterStats(t *testing.T) {
+	ln, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer ln.Close()
+
+	w := &writer{
+		address:         ln.LocalAddr().String(),
+		transport:       TransportUDP,
+		chunkSize:       DefaultChunkSize,
+		chunkDataSize:   DefaultChunkSize - chunkHeaderSize,
+		compressionType: CompressionNone,
+	}
+
+	if w.conn, err = w.dial(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if _, err = w.Write([]byte("sent")); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if _, err = w.Write(make([]byte, MaxChunkCount*DefaultChunkSize)); !errors.Is(err, ErrTooManyChunks) {
+		t.Fatal("expected too many chunks error but got:", err)
+	}
+
+	_ = w.Close()
+
+	if _, err = w.Write([]byte("closed")); err == nil {
+		t.Fatal("expected error writing to closed connection")
+	}
+
+	if stats := w.Stats(); stats != (Stats{Sent: 1, Dropped: 1, WriteErrors: 1}) {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestWriterMessageID(t *testing.T) {
+	w := &writer{idSeed: 0xdeadbeef}
+	ids := make(map[[8]byte]bool)
+
+	for i := 0; i < 100000; i++ {
+		id := w.messageID()
+		if ids[id] {
+			t.Fatalf("duplicate message id %x after %d messages", id, i)
+		}
+
+		ids[id] = true
+	}
+}
+
+func TestWriterCompressionThreshold(t *testing.T) {
+	w := &writer{compressionType: CompressionGzip, compressionLevel: gzip.BestSpeed, compressionMin: 64}
+
+	for _, msg := range []string{`{"short_message":"small"}`, `{"short_message":"` + strings.Repeat("large", 20) + `"}`} {
+		var buf bytes.Buffer
+		if err := w.compress(&buf, []byte(msg)); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		if compressed := !bytes.Equal(buf.Bytes(), []byte(msg)); compressed != (len(msg) >= 64) {
+			t.Fatalf("message of %d bytes compressed: %t", len(msg), compressed)
+		}
+	}
+}
+
+func TestExecutableName(t *testing.T) {
+	if name := executableName([]string{"/usr/local/bin/service", "-v"}); name != "service" {
+		t.Fatalf("unexpected name %q", name)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		t.Skip("executable is unknown:", err)
+	}
+
+	for _, args := range [][]string{nil, {""}} {
+		if name := executableName(args); name != filepath.Base(exe) {
+			t.Fatalf("unexpected name %q for %q", name, args)
+		}
+	}
+}
+
+func TestWriterChunkCount(t *testing.T) {
+	const dataSize = DefaultChunkSize - chunkHeaderSize
+
+	w := &writer{chunkSize: DefaultChunkSize, chunkDataSize: dataSize}
+
+	for size, expected := range map[int]int{
+		1:                    1,
+		DefaultChunkSize:     1,
+		DefaultChunkSize + 1: 2,
+		2 * dataSize:         2,
+		2*dataSize + 1:       3,
+		3 * dataSize:         3,
+	} {
+		if count := w.chunkCount(make([]byte, size)); count != expected {
+			t.Errorf("%d bytes: expected %d chunks but got %d", size, expected, count)
+		}
+	}
+
+	conn := &recordConn{}
+	w.conn, w.transport, w.compressionType = conn, TransportUDP, CompressionNone
+
+	payload := bytes.Repeat([]byte("x"), 3*dataSize)
+	if _, err := w.Write(payload); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	datagrams := conn.datagrams()
+	if len(datagrams) != 3 {
+		t.Fatalf("expected 3 chunks but got %d", len(datagrams))
+	}
+
+	for i, d := range datagrams {
+		if len(d) != DefaultChunkSize || d[11] != 3 {
+			t.Fatalf("chunk %d has %d bytes of %d chunks", i, len(d)-chunkHeaderSize, d[11])
+		}
+	}
+
+	if !bytes.Equal(reassemble(datagrams), payload) {
+		t.Fatal("reassembled message differs")
+	}
+}
+
+func TestWriterConcurrentChunks(t *testing.T) {
+	const (
+		goroutines = 16
+		messages   = 20
+		chunks     = 3
+	)
+
+	conn := &recordConn{}
+	w := &writer{
+		conn:            conn,
+		transport:       TransportUDP,
+		chunkSize:       DefaultChunkSize,
+		chunkDataSize:   DefaultChunkSize - chunkHeaderSize,
+		compressionType: CompressionNone,
+	}
+
+	payload := bytes.Repeat([]byte("x"), chunks*w.chunkDataSize)
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < messages; j++ {
+				if _, err := w.Write(payload); err != nil {
+					t.Error("error occurred:", err)
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	datagrams := conn.datagrams()
+	if len(datagrams) != goroutines*messages*chunks {
+		t.Fatalf("unexpected datagram count %d", len(datagrams))
+	}
+
+	// the chunks of every message are consecutive
+	for i, d := range datagrams {
+		first := datagrams[i-i%chunks]
+		if d[10] != byte(i%chunks) || !bytes.Equal(d[2:10], first[2:10]) {
+			t.Fatalf("chunk %d interleaves with another message", i)
+		}
+	}
+
+	if stats := w.Stats(); stats.Sent != goroutines*messages {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestDialUDPCandidates(t *testing.T) {
+	ln, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer ln.Close()
+
+	_, port, _ := net.SplitHostPort(ln.LocalAddr().String())
+
+	lookup := lookupIPAddr
+	defer func() { lookupIPAddr = lookup }()
+
+	// the first candidate can't be dialed, the zone is no interface
+	lookupIPAddr = func(context.Context, string) ([]net.IPAddr, error) {
+		return []net.IPAddr{
+			{IP: net.ParseIP("fe80::1"), Zone: "missing0"},
+			{IP: net.ParseIP("127.0.0.1")},
+		}, nil
+	}
+
+	conn, err := dialUDP(&net.Dialer{Timeout: time.Second}, net.JoinHostPort("graylog.test", port))
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer conn.Close()
+
+	if conn.RemoteAddr().String() != ln.LocalAddr().String() {
+		t.Fatalf("connected to %s", conn.RemoteAddr())
+	}
+
+	lookupIPAddr = func(context.Context, string) ([]net.IPAddr, error) { return nil, nil }
+
+	if _, err = dialUDP(&net.Dialer{Timeout: time.Second}, net.JoinHostPort("graylog.test", port)); err == nil {
+		t.Fatal("expected error without addresses")
+	}
+}
+
+func TestWriterFailback(t *testing.T) {
+	defer func(interval time.Duration) { failbackInterval = interval }(failbackInterval)
+	failbackInterval = 0
+
+	primary, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	// the primary is down when the writer is created
+	addr := primary.Addr().String()
+	_ = primary.Close()
+
+	secondary, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer secondary.Close()
+
+	w, err := newWriter(LoggingConfiguration{
+		GraylogAddress:    addr,
+		FailoverAddresses: []string{secondary.Addr().String()},
+		Transport:         TransportTCP,
+		DialTimeout:       time.Second,
+		WriteRetries:      1,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer w.Close()
+
+	w.mu.Lock()
+	current := w.current
+	w.mu.Unlock()
+
+	if current != 1 {
+		t.Fatalf("expected the secondary collector but got %d", current)
+	}
+
+	// the primary comes back on the same port
+	if primary, err = net.Listen("tcp", addr); err != nil {
+		t.Skip("primary port is reused:", err)
+	}
+	defer primary.Close()
+
+	for deadline := time.Now().Add(5 * time.Second); current != 0; {
+		if time.Now().After(deadline) {
+			t.Fatal("writer doesn't fail back to the primary collector")
+		}
+
+		w.connection()
+		time.Sleep(10 * time.Millisecond)
+
+		w.mu.Lock()
+		current = w.current
+		w.mu.Unlock()
+	}
+
+	if w.address != addr {
+		t.Fatalf("unexpected address %s after failback", w.address)
+	}
+}
+
+func TestBuildChunk(t *testing.T) {
+	id := [8]byte{1, 2, 3, 4, 5, 6, 7, 8}
+
+	chunk := buildChunk(nil, id, 2, 5, []byte("data"))
+
+	expected := []byte{0x1e, 0x0f, 1, 2, 3, 4, 5, 6, 7, 8, 2, 5, 'd', 'a', 't', 'a'}
+	if !bytes.Equal(chunk, expected) {
+		t.Fatalf("expected chunk % x but got % x", expected, chunk)
+	}
+
+	if header := chunk[:chunkHeaderSize]; !bytes.Equal(header[:2], chunkedMagicBytes) || len(header) != 12 {
+		t.Fatalf("unexpected header % x", header)
+	}
+
+	// the buffer is reused
+	buf := make([]byte, 0, 64)
+	if chunk = buildChunk(buf[:0], id, 0, 1, nil); len(chunk) != chunkHeaderSize || &chunk[0] != &buf[:1][0] {
+		t.Fatalf("unexpected empty chunk % x", chunk)
+	}
+}
@@ -1,8 +1,34 @@
 package logger_test
 
 import (
-	"go.cantor.systems/logger"
+	"bufio"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"go.cantor.systems/logger"
+	"go.cantor.systems/logger/internal/testutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 func TestNew(t *testing.T) {
@@ -19,4 +45,1229 @@ func TestNew(t *testing.T) {
 	if log == nil {
 		t.Fatal("nil apilog")
 	}
+
+	if err = log.Close(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+}
+
+func TestNewTCP(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		AppName:        "test",
+		Hostname:       "localhost",
+		Transport:      logger.TransportTCP,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	log.Info("hello")
+
+	if err = log.Close(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if msg := <-messages; msg["short_message"] != "hello" {
+		t.Fatalf("unexpected short_message: %v", msg["short_message"])
+	}
+
+	if _, ok := <-messages; ok {
+		t.Fatal("connection is not closed")
+	}
+}
+
+func TestNewUDP(t *testing.T) {
+	var long = strings.Repeat("chunked ", 500)
+
+	for _, test := range []struct {
+		name string
+		conf logger.LoggingConfiguration
+		msg  string
+	}{
+		{"plain", logger.LoggingConfiguration{CompressionType: logger.CompressionNone}, "plain"},
+		{"gzip", logger.LoggingConfiguration{CompressionType: logger.CompressionGzip, CompressionThreshold: -1}, "gzip"},
+		{"zlib", logger.LoggingConfiguration{CompressionType: logger.CompressionZlib, CompressionThreshold: -1}, "zlib"},
+		{"zstd", logger.LoggingConfiguration{CompressionType: logger.CompressionZstd, CompressionThreshold: -1}, "zstd"},
+		{"chunked", logger.LoggingConfiguration{CompressionType: logger.CompressionNone, ChunkSize: 512}, long},
+	} {
+		t.Run(test.name, func(t *testing.T) {
+			addr, messages, stop := testutil.NewUDPGraylog(t)
+			defer stop()
+
+			test.conf.GraylogAddress = addr
+			test.conf.AppName = "test"
+			test.conf.StaticFields = map[string]string{"environment": "ci"}
+
+			log, err := logger.New(test.conf)
+			if err != nil {
+				t.Fatal("error occurred:", err)
+			}
+			defer log.Close()
+
+			log.Info(test.msg, zap.Int("attempt", 1))
+
+			select {
+			case msg := <-messages:
+				if msg["short_message"] != test.msg || msg["app_name"] != "test" || msg["version"] != "1.1" ||
+					msg["environment"] != "ci" || msg["attempt"] != float64(1) || msg["level"] != float64(6) {
+					t.Fatalf("unexpected message: %v", msg)
+				}
+			case <-time.After(time.Second):
+				t.Fatal("message is not received")
+			}
+		})
+	}
+}
+
+func TestLogger_AtomicLevel(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Debug("hidden")
+	log.AtomicLevel().SetLevel(zapcore.DebugLevel)
+	log.Debug("visible")
+
+	if msg := <-messages; msg["short_message"] != "visible" {
+		t.Fatalf("unexpected short_message: %v", msg["short_message"])
+	}
+}
+
+func ExampleLogger_AtomicLevel() {
+	log, _ := logger.New(logger.LoggingConfiguration{Level: "info"})
+	defer log.Close()
+
+	fmt.Println(log.Core().Enabled(zapcore.DebugLevel))
+	log.AtomicLevel().SetLevel(zapcore.DebugLevel)
+	fmt.Println(log.Core().Enabled(zapcore.DebugLevel))
+	// Output:
+	// false
+	// true
+}
+
+func TestNewUnknownTransport(t *testing.T) {
+	if _, err := logger.New(logger.LoggingConfiguration{Transport: "sctp"}); err == nil {
+		t.Fatal("expected error for unknown transport")
+	}
+}
+
+func TestNewCompression(t *testing.T) {
+	for _, conf := range []logger.LoggingConfiguration{
+		{CompressionType: logger.CompressionNone},
+		{CompressionType: logger.CompressionZlib, CompressionLevel: 1},
+		{CompressionType: logger.CompressionGzip, CompressionLevel: 9},
+	} {
+		if _, err := logger.New(conf); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+	}
+
+	for _, conf := range []logger.LoggingConfiguration{
+		{CompressionType: 42},
+		{CompressionType: logger.CompressionGzip, CompressionLevel: 10},
+		{CompressionType: logger.CompressionZlib, CompressionLevel: -3},
+	} {
+		if _, err := logger.New(conf); err == nil {
+			t.Fatalf("expected error for %+v", conf)
+		}
+	}
+}
+
+func TestNewCompressionLevels(t *testing.T) {
+	for _, test := range []struct {
+		kind, level int
+		valid       bool
+	}{
+		{logger.CompressionNone, -100, true},
+		{logger.CompressionNone, 100, true},
+		{logger.CompressionGzip, gzip.HuffmanOnly - 1, false},
+		{logger.CompressionGzip, gzip.HuffmanOnly, true},
+		{logger.CompressionGzip, gzip.BestCompression, true},
+		{logger.CompressionGzip, gzip.BestCompression + 1, false},
+		{logger.CompressionZlib, zlib.HuffmanOnly - 1, false},
+		{logger.CompressionZlib, zlib.HuffmanOnly, true},
+		{logger.CompressionZlib, zlib.BestCompression, true},
+		{logger.CompressionZlib, zlib.BestCompression + 1, false},
+		{logger.CompressionZstd, -1, false},
+		{logger.CompressionZstd, int(zstd.SpeedFastest), true},
+		{logger.CompressionZstd, int(zstd.SpeedBestCompression), true},
+		{logger.CompressionZstd, int(zstd.SpeedBestCompression) + 1, false},
+	} {
+		_, err := logger.New(logger.LoggingConfiguration{CompressionType: test.kind, CompressionLevel: test.level})
+		if test.valid && err != nil {
+			t.Errorf("compression %d level %d: unexpected error %v", test.kind, test.level, err)
+		}
+
+		if !test.valid && err == nil {
+			t.Errorf("compression %d level %d: expected error", test.kind, test.level)
+		}
+	}
+}
+
+func TestNewChunkSize(t *testing.T) {
+	for _, size := range []int{12, logger.MaxChunkSize + 1} {
+		if _, err := logger.New(logger.LoggingConfiguration{ChunkSize: size}); err == nil {
+			t.Fatalf("expected error for chunk size %d", size)
+		}
+	}
+}
+
+func TestNewLevel(t *testing.T) {
+	log, err := logger.New(logger.LoggingConfiguration{Level: "warn"})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if log.Core().Enabled(zapcore.InfoLevel) || !log.Core().Enabled(zapcore.WarnLevel) {
+		t.Fatal("level is not applied")
+	}
+
+	if _, err = logger.New(logger.LoggingConfiguration{Level: "verbose"}); err == nil {
+		t.Fatal("expected error for unknown level")
+	}
+}
+
+// newTCPGraylog starts a TCP GELF listener and returns its address and the decoded messages.
+// The channel is closed when the logger closes its connection.
+func newTCPGraylog(t *testing.T) (string, <-chan map[string]interface{}) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	return ln.Addr().String(), serveGraylog(t, ln)
+}
+
+// serveGraylog decodes the null byte terminated messages of the first connection to ln.
+func serveGraylog(t *testing.T, ln net.Listener) <-chan map[string]interface{} {
+	messages := make(chan map[string]interface{}, 16)
+
+	go func() {
+		defer close(messages)
+		defer ln.Close()
+
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		for {
+			frame, err := r.ReadBytes(0x00)
+			if err != nil {
+				return
+			}
+
+			var msg map[string]interface{}
+			if err = json.Unmarshal(frame[:len(frame)-1], &msg); err != nil {
+				t.Error("message is not plain JSON:", err)
+				return
+			}
+
+			messages <- msg
+		}
+	}()
+
+	return messages
+}
+
+func TestNewMirrorToStdout(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		AppName:        "test",
+		Transport:      logger.TransportTCP,
+		MirrorToStdout: true,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("mirrored")
+
+	msg := <-messages
+	if msg["short_message"] != "mirrored" || msg["app_name"] != "test" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+}
+
+func TestNewGELFVersion(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("versioned")
+
+	// a number would decode as float64
+	if version, ok := (<-messages)["version"].(string); !ok || version != logger.GELFVersion {
+		t.Fatalf("version must be the string %q", logger.GELFVersion)
+	}
+}
+
+func TestNewCallerStacktrace(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress:   addr,
+		Transport:        logger.TransportTCP,
+		EnableCaller:     true,
+		EnableStacktrace: true,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("info")
+	log.Error("error")
+
+	if msg := <-messages; !strings.Contains(fmt.Sprint(msg["_caller"]), "/logger_test.go:") || msg["full_message"] != nil {
+		t.Fatalf("unexpected info message: %v", msg)
+	}
+
+	if msg := <-messages; !strings.Contains(fmt.Sprint(msg["full_message"]), "TestNewCallerStacktrace") {
+		t.Fatalf("unexpected error message: %v", msg)
+	}
+}
+
+func TestNewCallerSkip(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+		EnableCaller:   true,
+		CallerSkip:     1,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	// a facade logging on behalf of its caller
+	var info = func(msg string) {
+		log.Info(msg)
+	}
+
+	_, file, line, _ := runtime.Caller(0)
+	info("wrapped")
+
+	var caller = fmt.Sprintf("%s:%d", filepath.Base(file), line+1)
+	if msg := <-messages; !strings.HasSuffix(fmt.Sprint(msg["_caller"]), "/"+caller) {
+		t.Fatalf("caller must be %s but is %v", caller, msg["_caller"])
+	}
+
+	if _, err = logger.New(logger.LoggingConfiguration{CallerSkip: -1}); err == nil {
+		t.Fatal("expected error for negative caller skip")
+	}
+}
+
+func TestNewConsoleTimeEncoder(t *testing.T) {
+	console, err := ioutil.TempFile("", "console")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer os.Remove(console.Name())
+	defer console.Close()
+
+	// zap opens stderr when the logger is built
+	stderr := os.Stderr
+	os.Stderr = console
+	defer func() { os.Stderr = stderr }()
+
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+		MirrorToStdout: true,
+	})
+	os.Stderr = stderr
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	log.Info("timed")
+	_ = log.Close()
+
+	if msg := <-messages; fmt.Sprintf("%T", msg["timestamp"]) != "float64" {
+		t.Fatalf("GELF timestamp must be numeric but is %v", msg["timestamp"])
+	}
+
+	if _, err = console.Seek(0, io.SeekStart); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	var line map[string]interface{}
+	if err = json.NewDecoder(console).Decode(&line); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if ts, ok := line["timestamp"].(string); !ok || !strings.HasPrefix(ts, strconv.Itoa(time.Now().Year())+"-") {
+		t.Fatalf("console timestamp must be ISO8601 but is %v", line["timestamp"])
+	}
+}
+
+func TestNewConcurrent(t *testing.T) {
+	const (
+		goroutines = 8
+		messages   = 50
+	)
+
+	addr, received := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < messages; j++ {
+				log.Info("concurrent", zap.Int("goroutine", i), zap.Int("message", j))
+			}
+		}(i)
+	}
+
+	// serveGraylog fails the test on frames corrupted by interleaved writes
+	for i := 0; i < goroutines*messages; i++ {
+		if msg := <-received; msg["short_message"] != "concurrent" {
+			t.Fatalf("unexpected message: %v", msg)
+		}
+	}
+
+	wg.Wait()
+}
+
+func TestNewDialTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer ln.Close()
+
+	// the listener accepts the connection, but the TLS handshake never completes
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+
+	start := time.Now()
+
+	_, err = logger.New(logger.LoggingConfiguration{
+		GraylogAddress:     ln.Addr().String(),
+		Transport:          logger.TransportTCP,
+		TLSConfig:          &tls.Config{},
+		DialTimeout:        100 * time.Millisecond,
+		FailOnGraylogError: true,
+	})
+
+	var gErr *logger.GraylogError
+	if !errors.As(err, &gErr) {
+		t.Fatal("expected *GraylogError but got:", err)
+	}
+
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("dial took %s", elapsed)
+	}
+
+	if _, err = logger.New(logger.LoggingConfiguration{DialTimeout: -time.Second}); err == nil {
+		t.Fatal("expected error for negative dial timeout")
+	}
+}
+
+func TestNewFailOnGraylogError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	// nothing listens on a closed port
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	conf := logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+	}
+
+	if _, err = logger.New(conf); err != nil {
+		t.Fatal("expected fallback but got:", err)
+	}
+
+	conf.FailOnGraylogError = true
+
+	var gErr *logger.GraylogError
+	if _, err = logger.New(conf); !errors.As(err, &gErr) || gErr.Address != addr {
+		t.Fatal("expected *GraylogError but got:", err)
+	}
+}
+
+func TestNewWithOptions(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.NewWithOptions(
+		logger.WithGraylog(addr),
+		logger.WithTransport(logger.TransportTCP),
+		logger.WithAppName("test"),
+		logger.WithHostname("localhost"),
+		logger.WithLevel(zapcore.DebugLevel),
+	)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Debug("options")
+
+	msg := <-messages
+	if msg["short_message"] != "options" || msg["app_name"] != "test" || msg["host"] != "localhost" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+
+	if _, err = logger.NewWithOptions(logger.WithCompression(logger.CompressionGzip, 42)); err == nil {
+		t.Fatal("expected error for invalid compression level")
+	}
+}
+
+func TestNewStaticFields(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+		StaticFields:   map[string]string{"environment": "staging", "git_commit": "abc123"},
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("static")
+
+	msg := <-messages
+	if msg["environment"] != "staging" || msg["git_commit"] != "abc123" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+
+	for _, key := range []string{"short_message", "version", "host"} {
+		_, err = logger.New(logger.LoggingConfiguration{StaticFields: map[string]string{key: "x"}})
+		if err == nil {
+			t.Fatalf("expected error for reserved field %q", key)
+		}
+	}
+}
+
+func TestNewAsync(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+		Async:          true,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	for i := 0; i < 10; i++ {
+		log.Info("async", zap.Int("i", i))
+	}
+
+	if err = log.Close(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	var received int
+	for msg := range messages {
+		if msg["i"] != float64(received) {
+			t.Fatalf("unexpected message: %v", msg)
+		}
+
+		received++
+	}
+
+	if received != 10 || log.Dropped() != 0 {
+		t.Fatalf("received %d messages, dropped %d", received, log.Dropped())
+	}
+}
+
+func TestNewFlush(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+		Async:          true,
+		BatchSize:      1 << 20,
+		BatchInterval:  time.Hour,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("first")
+	log.Info("second")
+
+	select {
+	case msg := <-messages:
+		t.Fatalf("message is sent before flush: %v", msg)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	for i := 0; i < 2; i++ {
+		if err = log.Flush(); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+	}
+
+	for _, expected := range []string{"first", "second"} {
+		if msg := <-messages; msg["short_message"] != expected {
+			t.Fatalf("unexpected message: %v", msg)
+		}
+	}
+}
+
+func TestNewGraylogAddress(t *testing.T) {
+	for address, valid := range map[string]bool{
+		"localhost":       false,
+		":12201":          false,
+		"localhost:":      false,
+		"localhost:12201": true,
+		"127.0.0.1:12201": true,
+		"[::1]:12201":     true,
+	} {
+		log, err := logger.New(logger.LoggingConfiguration{GraylogAddress: address})
+		if valid && err != nil {
+			t.Errorf("%s: unexpected error %s", address, err)
+		}
+
+		if !valid && err == nil {
+			t.Errorf("%s: expected error", address)
+		}
+
+		if log != nil {
+			_ = log.Close()
+		}
+	}
+}
+
+func TestNewHostname(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skip("no hostname:", err)
+	}
+
+	for configured, expected := range map[string]string{"": hostname, "explicit": "explicit"} {
+		addr, messages := newTCPGraylog(t)
+
+		log, err := logger.New(logger.LoggingConfiguration{
+			GraylogAddress: addr,
+			Transport:      logger.TransportTCP,
+			Hostname:       configured,
+		})
+		if err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		log.Info("hostname")
+		_ = log.Close()
+
+		if msg := <-messages; msg["host"] != expected {
+			t.Errorf("expected host %q but got %v", expected, msg["host"])
+		}
+	}
+}
+
+func TestNewHostnameFunc(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	var hostname atomic.Value
+	hostname.Store("node-a")
+
+	log, err := logger.NewWithOptions(
+		logger.WithGraylog(addr),
+		logger.WithTransport(logger.TransportTCP),
+		logger.WithHostnameFunc(func() string { return hostname.Load().(string) }),
+	)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	child := log.With(zap.String("component", "db"))
+
+	child.Info("before")
+	hostname.Store("node-b")
+	child.Info("after")
+
+	for _, expected := range []string{"node-a", "node-b"} {
+		if msg := <-messages; msg["host"] != expected {
+			t.Fatalf("expected host %q but got %v", expected, msg["host"])
+		}
+	}
+}
+
+func TestNewTLS(t *testing.T) {
+	// borrow the httptest certificate, valid for 127.0.0.1
+	srv := httptest.NewTLSServer(nil)
+	srv.Close()
+
+	ln, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: srv.TLS.Certificates})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	messages := serveGraylog(t, ln)
+
+	// the unknown authority is rejected
+	_, err = logger.New(logger.LoggingConfiguration{
+		GraylogAddress:     ln.Addr().String(),
+		TLSConfig:          &tls.Config{},
+		FailOnGraylogError: true,
+	})
+	if err == nil {
+		t.Fatal("expected certificate verification error")
+	}
+
+	ln, err = tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: srv.TLS.Certificates})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	<-messages
+	messages = serveGraylog(t, ln)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+
+	log, err := logger.NewWithOptions(
+		logger.WithGraylog(ln.Addr().String()),
+		logger.WithTLS(&tls.Config{RootCAs: roots}),
+		logger.WithFailOnGraylogError(),
+	)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	log.Info("secure")
+	_ = log.Close()
+
+	if msg := <-messages; msg["short_message"] != "secure" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+
+	_, err = logger.New(logger.LoggingConfiguration{TLSConfig: &tls.Config{}, Transport: logger.TransportUDP})
+	if err == nil {
+		t.Fatal("expected error for TLS over UDP")
+	}
+}
+
+func TestNewUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gelf")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer os.RemoveAll(dir)
+
+	socket := filepath.Join(dir, "gelf.sock")
+
+	for _, conf := range []logger.LoggingConfiguration{
+		{GraylogAddress: "unix://" + socket},
+		{GraylogAddress: socket, Transport: logger.TransportUnix},
+	} {
+		ln, err := net.Listen("unix", socket)
+		if err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		messages := serveGraylog(t, ln)
+
+		conf.FailOnGraylogError = true
+
+		log, err := logger.New(conf)
+		if err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		log.Info("local")
+		_ = log.Close()
+
+		if msg := <-messages; msg["short_message"] != "local" {
+			t.Fatalf("unexpected message: %v", msg)
+		}
+	}
+
+	if _, err = logger.New(logger.LoggingConfiguration{GraylogAddress: "unix://" + socket, Transport: logger.TransportUDP}); err == nil {
+		t.Fatal("expected error for unix socket over UDP")
+	}
+}
+
+func TestNamed(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		AppName:        "api",
+		Transport:      logger.TransportTCP,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("root")
+	logger.Named(log.Logger, "db").Info("child")
+
+	if msg := <-messages; msg["_logger"] != "api" {
+		t.Fatalf("unexpected root _logger %v", msg["_logger"])
+	}
+
+	if msg := <-messages; msg["_logger"] != "api.db" {
+		t.Fatalf("unexpected child _logger %v", msg["_logger"])
+	}
+}
+
+func TestNewIPv6(t *testing.T) {
+	ln, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skip("no IPv6 loopback:", err)
+	}
+
+	messages := serveGraylog(t, ln)
+
+	udp, err := net.ListenPacket("udp", "[::1]:0")
+	if err != nil {
+		t.Skip("no IPv6 loopback:", err)
+	}
+	defer udp.Close()
+
+	for _, conf := range []logger.LoggingConfiguration{
+		{GraylogAddress: ln.Addr().String(), Transport: logger.TransportTCP},
+		{GraylogAddress: udp.LocalAddr().String(), CompressionType: logger.CompressionNone},
+	} {
+		if conf.GraylogAddress[0] != '[' {
+			t.Fatalf("address %s is not bracketed", conf.GraylogAddress)
+		}
+
+		log, err := logger.New(conf)
+		if err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		log.Info("ipv6")
+		_ = log.Close()
+	}
+
+	if msg := <-messages; msg["short_message"] != "ipv6" {
+		t.Fatalf("unexpected TCP message: %v", msg)
+	}
+
+	buf := make([]byte, logger.MaxChunkSize)
+	_ = udp.SetReadDeadline(time.Now().Add(time.Second))
+
+	n, _, err := udp.ReadFrom(buf)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	var msg map[string]interface{}
+	if err = json.Unmarshal(buf[:n], &msg); err != nil || msg["short_message"] != "ipv6" {
+		t.Fatalf("unexpected UDP message %q: %v", buf[:n], err)
+	}
+}
+
+func TestNewStructuredErrors(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress:   addr,
+		Transport:        logger.TransportTCP,
+		EnableStacktrace: true,
+		StructuredErrors: true,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	wrapped := fmt.Errorf("save user: %w", fmt.Errorf("query: %w", io.EOF))
+
+	log.Error("failed", zap.Error(wrapped))
+	log.With(zap.Error(io.EOF)).Warn("context error", zap.String("error", "plain string"))
+
+	msg := <-messages
+	if _, ok := msg["error"]; ok {
+		t.Fatalf("error field is kept: %v", msg)
+	}
+
+	if e, _ := msg["_error"].(map[string]interface{}); e["message"] != wrapped.Error() || e["type"] != "*fmt.wrapError" {
+		t.Fatalf("unexpected _error %v", msg["_error"])
+	}
+
+	chain := fmt.Sprint(msg["_error_chain"])
+	if chain != "[save user: query: EOF query: EOF EOF]" {
+		t.Fatalf("unexpected _error_chain %s", chain)
+	}
+
+	if !strings.Contains(fmt.Sprint(msg["full_message"]), "TestNewStructuredErrors") {
+		t.Fatalf("missing stack in full_message: %v", msg["full_message"])
+	}
+
+	msg = <-messages
+	if e, _ := msg["_error"].(map[string]interface{}); e["message"] != "EOF" || msg["error"] != "plain string" {
+		t.Fatalf("unexpected context error fields %v", msg)
+	}
+}
+
+func TestNewOmitFields(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.NewWithOptions(
+		logger.WithGraylog(addr),
+		logger.WithTransport(logger.TransportTCP),
+		logger.WithAppName("test"),
+		logger.WithOmitFields("pid", "exe"),
+	)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("omitted")
+
+	msg := <-messages
+	for _, key := range []string{"pid", "exe"} {
+		if _, ok := msg[key]; ok {
+			t.Errorf("%s is not omitted: %v", key, msg)
+		}
+	}
+
+	if msg["version"] != logger.GELFVersion || msg["app_name"] != "test" || msg["host"] == nil {
+		t.Fatalf("kept fields are missing: %v", msg)
+	}
+
+	for _, key := range []string{"version", "host", "app_name", "unknown"} {
+		if _, err = logger.New(logger.LoggingConfiguration{OmitFields: []string{key}}); err == nil {
+			t.Errorf("expected error omitting %s", key)
+		}
+	}
+}
+
+func TestNewCallerFields(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.NewWithOptions(
+		logger.WithGraylog(addr),
+		logger.WithTransport(logger.TransportTCP),
+		logger.WithCallerFields(),
+	)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("caller")
+
+	msg := <-messages
+	file, _ := msg["_file"].(string)
+	line, _ := msg["_line"].(float64) // JSON numbers decode as float64
+
+	if !strings.HasSuffix(file, "/logger_test.go") || line <= 0 {
+		t.Fatalf("unexpected _file %v and _line %v", msg["_file"], msg["_line"])
+	}
+
+	if caller := fmt.Sprint(msg["_caller"]); caller != fmt.Sprintf("%s:%d", file, int(line)) {
+		t.Fatalf("unexpected _caller %s", caller)
+	}
+
+	if _, err = logger.New(logger.LoggingConfiguration{CallerFields: true}); err == nil {
+		t.Fatal("expected error for CallerFields without EnableCaller")
+	}
+}
+
+func TestNewFailover(t *testing.T) {
+	primary, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	secondaryAddr, secondary := newTCPGraylog(t)
+
+	log, err := logger.NewWithOptions(
+		logger.WithGraylog(primary.Addr().String()),
+		logger.WithTransport(logger.TransportTCP),
+		logger.WithFailover(secondaryAddr),
+	)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	conn, err := primary.Accept()
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	log.Info("primary")
+
+	frame, err := bufio.NewReader(conn).ReadBytes(0x00)
+	if err != nil || !strings.Contains(string(frame), `"short_message":"primary"`) {
+		t.Fatalf("unexpected primary frame %q: %v", frame, err)
+	}
+
+	// the primary goes down mid-stream, writes fail once the peer resets the connection
+	_ = primary.Close()
+	_ = conn.Close()
+
+	deadline := time.After(5 * time.Second)
+	for i := 0; ; i++ {
+		log.Info("failover", zap.Int("i", i))
+
+		select {
+		case msg := <-secondary:
+			if msg["short_message"] != "failover" {
+				t.Fatalf("unexpected secondary message %v", msg)
+			}
+
+			return
+		case <-deadline:
+			t.Fatal("no message reached the secondary collector")
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+}
+
+func TestNewFailoverAddresses(t *testing.T) {
+	for _, conf := range []logger.LoggingConfiguration{
+		{GraylogAddress: "localhost:12201", FailoverAddresses: []string{"localhost"}},
+		{GraylogAddress: "http://graylog:12201/gelf", FailoverAddresses: []string{"http://backup:12201/gelf"}},
+	} {
+		if _, err := logger.New(conf); err == nil {
+			t.Errorf("expected error for %+v", conf)
+		}
+	}
+}
+
+func TestNewFailoverAddressesUnchanged(t *testing.T) {
+	failover := []string{"unix:///nonexistent/graylog.sock"}
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress:    "/nonexistent/graylog.sock",
+		Transport:         logger.TransportUnix,
+		FailoverAddresses: failover,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	_ = log.Close()
+
+	if failover[0] != "unix:///nonexistent/graylog.sock" {
+		t.Fatalf("the configuration is modified: %q", failover)
+	}
+}
+
+func TestNewOverflowPolicy(t *testing.T) {
+	log, err := logger.NewWithOptions(logger.WithAsync(4), logger.WithOverflowPolicy(logger.OverflowBlock))
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	_ = log.Close()
+
+	if _, err = logger.New(logger.LoggingConfiguration{Async: true, OverflowPolicy: 42}); err == nil {
+		t.Fatal("expected error for unknown overflow policy")
+	}
+}
+
+func TestNewClock(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+	clock := func() time.Time { return time.Unix(1600000000, 0) }
+
+	log, err := logger.NewWithOptions(
+		logger.WithGraylog(addr),
+		logger.WithTransport(logger.TransportTCP),
+		logger.WithClock(clock),
+	)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("pinned")
+
+	if msg := <-messages; msg["timestamp"] != float64(1600000000) {
+		t.Fatalf("unexpected timestamp %v", msg["timestamp"])
+	}
+}
+
+func TestLogger_Component(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{GraylogAddress: addr, Transport: logger.TransportTCP})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = log.Component("db")
+		}()
+	}
+	wg.Wait()
+
+	db := log.Component("db")
+	if log.Component("db") != db || log.Component("http") == db {
+		t.Fatal("component loggers are not cached by name")
+	}
+
+	db.Info("query")
+
+	if msg := <-messages; msg["component"] != "db" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+}
+
+func TestNewRawGELF(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer conn.Close()
+
+	var (
+		raw         = logger.WithRawGELF()
+		compression = logger.WithCompression(logger.CompressionZlib, 9)
+	)
+
+	// raw GELF wins whatever the order of the options
+	for _, options := range [][]logger.Option{{compression, raw}, {raw, compression}} {
+		log, err := logger.NewWithOptions(append(options,
+			logger.WithGraylog(conn.LocalAddr().String()),
+			logger.WithChunkSize(8192),
+		)...)
+		if err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		// above the compression threshold
+		var long = strings.Repeat("raw ", 1000)
+		log.Info(long)
+		_ = log.Close()
+
+		buf := make([]byte, logger.MaxChunkSize)
+		_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		var msg map[string]interface{}
+		if err = json.Unmarshal(buf[:n], &msg); err != nil || msg["short_message"] != long {
+			t.Fatalf("datagram is not the uncompressed message %q: %v", buf[:n], err)
+		}
+	}
+}
+
+func TestNewFieldPrefix(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.NewWithOptions(
+		logger.WithGraylog(addr),
+		logger.WithTransport(logger.TransportTCP),
+		logger.WithAppName("api"),
+		logger.WithStaticFields(map[string]string{"environment": "ci"}),
+		logger.WithFieldPrefix("app."),
+	)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.With(zap.String("tenant", "acme")).Info("prefixed", zap.Int("user_id", 7), zap.Any("request", map[string]string{"path": "/"}))
+
+	msg := <-messages
+	for _, key := range []string{"app.environment", "app.tenant", "app.user_id", "app.request", "short_message", "version", "host", "timestamp", "app_name", "pid"} {
+		if _, ok := msg[key]; !ok {
+			t.Errorf("missing %s in %v", key, msg)
+		}
+	}
+
+	if request, _ := msg["app.request"].(map[string]interface{}); request["path"] != "/" {
+		t.Errorf("nested keys are prefixed in %v", msg)
+	}
+
+	for _, key := range []string{"environment", "tenant", "user_id", "app.host", "app.version"} {
+		if _, ok := msg[key]; ok {
+			t.Errorf("unexpected %s in %v", key, msg)
+		}
+	}
+
+	if _, err = logger.New(logger.LoggingConfiguration{FieldPrefix: "app/"}); err == nil {
+		t.Fatal("expected error for invalid prefix")
+	}
+}
+
+func TestLogger_Count(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{GraylogAddress: addr, Transport: logger.TransportTCP})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Count("orders_created", math.Inf(1))
+	log.Count("orders_created", 2, zap.String("tenant", "acme"))
+
+	msg := <-messages
+	if msg["_metric_name"] != "orders_created" || msg["tenant"] != "acme" || msg["level_name"] != "INFO" {
+		t.Fatalf("unexpected metric message: %v", msg)
+	}
+
+	// numbers decode as float64, strings would not
+	if value, ok := msg["_metric_value"].(float64); !ok || value != 2 {
+		t.Fatalf("metric value must be the number 2 but is %#v", msg["_metric_value"])
+	}
 }
@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"math"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// metricNameKey is the field of the metric name of Count messages.
+	metricNameKey = "_metric_name"
+
+	// metricValueKey is the numeric field of the metric value of Count messages.
+	metricValueKey = "_metric_value"
+)
+
+// Count logs the metric event of name at info level, with the _metric_name and numeric _metric_value
+// fields after fields, for lightweight counters charted in Graylog, e.g. log.Count("orders_created", 1).
+// It is best-effort like any log message: events are sampled, dropped and lost like the others,
+// so it doesn't replace a metrics system. NaN and infinite values, which JSON can't encode, are skipped.
+func (l *Logger) Count(name string, value float64, fields ...zap.Field) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+
+	l.Info("metric "+name, append(fields[:len(fields):len(fields)],
+		zap.String(metricNameKey, name),
+		zap.Float64(metricValueKey, value),
+	)...)
+}
@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// NewNop returns a logger that discards everything, for tests of code taking a *zap.Logger.
+func NewNop() *zap.Logger {
+	return zap.New(zapcore.NewNopCore())
+}
+
+// NewObserver returns a logger recording entries of all levels in memory, for tests
+// asserting on emitted entries. The entries carry the same pid, host, exe and version
+// fields as loggers created by New, app_name is empty.
+func NewObserver() (*zap.Logger, *observer.ObservedLogs) {
+	var configuration LoggingConfiguration
+	_ = configuration.normalize() // cannot fail for the zero configuration
+
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	return zap.New(core, zap.Fields(gelfFields(configuration)...)), logs
+}
@@ -0,0 +1,28 @@
+package logger_test
+
+import (
+	"fmt"
+	"testing"
+
+	"go.cantor.systems/logger"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewNop(t *testing.T) {
+	log := logger.NewNop()
+	log.Info("discarded")
+
+	if log.Core().Enabled(zapcore.FatalLevel) {
+		t.Fatal("nop logger should not be enabled")
+	}
+}
+
+func ExampleNewObserver() {
+	log, logs := logger.NewObserver()
+	log.Info("hello")
+
+	entry := logs.All()[0]
+	fmt.Println(entry.Message, entry.ContextMap()["version"])
+	// Output:
+	// hello 1.1
+}
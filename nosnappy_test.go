@@ -0,0 +1,16 @@
+//go:build !snappy
+// +build !snappy
+
+package logger_test
+
+import (
+	"testing"
+
+	"go.cantor.systems/logger"
+)
+
+func TestNewSnappyWithoutTag(t *testing.T) {
+	if _, err := logger.New(logger.LoggingConfiguration{CompressionType: logger.CompressionSnappy}); err == nil {
+		t.Fatal("expected error without the snappy build tag")
+	}
+}
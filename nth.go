@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultNthSamplingReset is used for an unset NthSamplingConfig.Reset.
+const DefaultNthSamplingReset = time.Minute
+
+type (
+	// NthSamplingConfig logs the first entry with a level and message, then every Nth one:
+	// the 1st, Nth, 2Nth and so on. It is a deterministic alternative to Sampling for loops logging
+	// the same message, counting per message rather than per second. Errors always pass.
+	NthSamplingConfig struct {
+		// N is the sampling rate, N <= 1 logs every entry.
+		N int
+
+		// Reset is how often the counters are cleared, bounding the memory of distinct messages,
+		// zero means DefaultNthSamplingReset. The first entry after a reset is logged again.
+		Reset time.Duration
+	}
+
+	// nthSamplerCore drops the entries between every Nth one of a message.
+	nthSamplerCore struct {
+		zapcore.Core
+
+		state *nthSamplerState
+	}
+
+	// nthSamplerState are the counters shared by the core and its children.
+	nthSamplerState struct {
+		n     int
+		reset time.Duration
+		now   func() time.Time
+
+		mu        sync.Mutex
+		counts    map[nthSamplerKey]int
+		lastReset time.Time
+	}
+
+	// nthSamplerKey identifies the sampled entries.
+	nthSamplerKey struct {
+		level   zapcore.Level
+		message string
+	}
+)
+
+// newNthSamplerCore wraps core, logging the first and every config.N-th entry of a message.
+func newNthSamplerCore(core zapcore.Core, config NthSamplingConfig) zapcore.Core {
+	if config.Reset == 0 {
+		config.Reset = DefaultNthSamplingReset
+	}
+
+	return &nthSamplerCore{
+		Core: core,
+		state: &nthSamplerState{
+			n:         config.N,
+			reset:     config.Reset,
+			now:       time.Now,
+			counts:    make(map[nthSamplerKey]int),
+			lastReset: time.Now(),
+		},
+	}
+}
+
+// With implements zapcore.Core.
+func (c *nthSamplerCore) With(fields []zapcore.Field) zapcore.Core {
+	return &nthSamplerCore{Core: c.Core.With(fields), state: c.state}
+}
+
+// Check implements zapcore.Core.
+func (c *nthSamplerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if !c.Enabled(ent.Level) {
+		return ce
+	}
+
+	if ent.Level < zapcore.ErrorLevel && !c.state.allow(ent) {
+		return ce
+	}
+
+	return c.Core.Check(ent, ce)
+}
+
+// allow counts ent and reports whether it is the first or a multiple of n.
+func (s *nthSamplerState) allow(ent zapcore.Entry) bool {
+	if s.n <= 1 {
+		return true
+	}
+
+	var key = nthSamplerKey{level: ent.Level, message: ent.Message}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now := s.now(); now.Sub(s.lastReset) >= s.reset {
+		s.counts = make(map[nthSamplerKey]int)
+		s.lastReset = now
+	}
+
+	s.counts[key]++
+
+	var count = s.counts[key]
+
+	return count == 1 || count%s.n == 0
+}
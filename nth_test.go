@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestNthSamplerCore(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	core := newNthSamplerCore(observed, NthSamplingConfig{N: 10, Reset: time.Minute}).(*nthSamplerCore)
+
+	var now = time.Unix(1000, 0)
+	core.state.now = func() time.Time { return now }
+	core.state.lastReset = now
+
+	log := zap.New(core).With(zap.String("k", "v"))
+
+	for i := 1; i <= 25; i++ {
+		log.Info("polling", zap.Int("i", i))
+		log.Warn("polling")
+		log.Error("failed")
+	}
+
+	var passed []int64
+	for _, e := range logs.FilterMessage("polling").FilterField(zap.String("k", "v")).All() {
+		if e.Level == zapcore.InfoLevel {
+			passed = append(passed, e.ContextMap()["i"].(int64))
+		}
+	}
+
+	if len(passed) != 3 || passed[0] != 1 || passed[1] != 10 || passed[2] != 20 {
+		t.Fatalf("expected the 1st, 10th and 20th entries but got %v", passed)
+	}
+
+	// the levels are counted apart and errors always pass
+	if n := logs.FilterMessage("polling").Len(); n != 6 {
+		t.Fatalf("expected 6 polling entries but got %d", n)
+	}
+
+	if n := logs.FilterMessage("failed").Len(); n != 25 {
+		t.Fatalf("expected 25 errors but got %d", n)
+	}
+
+	// the counters restart after the reset
+	now = now.Add(time.Minute)
+	log.Info("polling", zap.Int("i", 26))
+
+	if last := logs.All()[logs.Len()-1]; last.ContextMap()["i"] != int64(26) {
+		t.Fatalf("first entry after reset is dropped: %v", last)
+	}
+}
+
+func TestNthSamplerCoreEvery(t *testing.T) {
+	observed, logs := observer.New(zapcore.DebugLevel)
+	log := zap.New(newNthSamplerCore(observed, NthSamplingConfig{N: 1}))
+
+	for i := 0; i < 5; i++ {
+		log.Info("all")
+	}
+
+	if logs.Len() != 5 {
+		t.Fatalf("expected 5 entries but got %d", logs.Len())
+	}
+}
+
+func TestNewNthSamplingInvalidReset(t *testing.T) {
+	if _, err := New(LoggingConfiguration{NthSampling: &NthSamplingConfig{N: 10, Reset: -time.Second}}); err == nil {
+		t.Fatal("expected error for negative reset")
+	}
+}
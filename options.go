@@ -0,0 +1,400 @@
+package logger
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Option configures the logger created by NewWithOptions.
+type Option func(*LoggingConfiguration)
+
+// NewWithOptions creates new apilog configured by options.
+func NewWithOptions(opts ...Option) (*Logger, error) {
+	var configuration LoggingConfiguration
+	for _, opt := range opts {
+		opt(&configuration)
+	}
+
+	return New(configuration)
+}
+
+// WithGraylog sends logs to the Graylog GELF input at address.
+func WithGraylog(address string) Option {
+	return func(c *LoggingConfiguration) {
+		c.GraylogAddress = address
+	}
+}
+
+// WithFailover adds collectors used in turn when the current one is unreachable.
+func WithFailover(addresses ...string) Option {
+	return func(c *LoggingConfiguration) {
+		c.FailoverAddresses = append(c.FailoverAddresses, addresses...)
+	}
+}
+
+// WithFallbackBuffer keeps up to count messages and maxBytes bytes while Graylog is unreachable at startup
+// and replays them once it is connected.
+func WithFallbackBuffer(count, maxBytes int) Option {
+	return func(c *LoggingConfiguration) {
+		c.FallbackBuffer = count
+		c.FallbackBufferBytes = maxBytes
+	}
+}
+
+// WithHTTPClient posts the messages of the HTTP transport with client, e.g. to set a proxy or timeouts.
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *LoggingConfiguration) {
+		c.HTTPClient = client
+	}
+}
+
+// WithKafka produces the messages to the Kafka topic with producer, see TransportKafka.
+func WithKafka(producer KafkaProducer, topic string) Option {
+	return func(c *LoggingConfiguration) {
+		c.Transport = TransportKafka
+		c.GraylogAddress = topic
+		c.KafkaProducer = producer
+	}
+}
+
+// WithTransport sets the Graylog transport, TransportUDP, TransportTCP, TransportUnix,
+// TransportHTTP or TransportKafka, which also needs the producer of WithKafka.
+func WithTransport(transport string) Option {
+	return func(c *LoggingConfiguration) {
+		c.Transport = transport
+	}
+}
+
+// WithAppName sets the app_name field.
+func WithAppName(name string) Option {
+	return func(c *LoggingConfiguration) {
+		c.AppName = name
+	}
+}
+
+// WithHostname sets the host field.
+func WithHostname(hostname string) Option {
+	return func(c *LoggingConfiguration) {
+		c.Hostname = hostname
+	}
+}
+
+// WithCompression sets the compression type and level.
+func WithCompression(kind, level int) Option {
+	return func(c *LoggingConfiguration) {
+		c.CompressionType = kind
+		c.CompressionLevel = level
+	}
+}
+
+// WithLevel sets the minimal enabled level.
+func WithLevel(level zapcore.Level) Option {
+	return func(c *LoggingConfiguration) {
+		c.Level = level.String()
+	}
+}
+
+// WithChunkSize sets the maximal UDP datagram size.
+func WithChunkSize(size int) Option {
+	return func(c *LoggingConfiguration) {
+		c.ChunkSize = size
+	}
+}
+
+// WithMirrorToStdout keeps the console output when Graylog is used.
+func WithMirrorToStdout() Option {
+	return func(c *LoggingConfiguration) {
+		c.MirrorToStdout = true
+	}
+}
+
+// WithFailOnGraylogError makes the constructor fail when Graylog is unreachable.
+func WithFailOnGraylogError() Option {
+	return func(c *LoggingConfiguration) {
+		c.FailOnGraylogError = true
+	}
+}
+
+// WithCaller adds the "_caller" field to every message.
+func WithCaller() Option {
+	return func(c *LoggingConfiguration) {
+		c.EnableCaller = true
+	}
+}
+
+// WithCallerFields adds the calling file and line as "_caller" and as separate "_file" and "_line" fields.
+func WithCallerFields() Option {
+	return func(c *LoggingConfiguration) {
+		c.EnableCaller = true
+		c.CallerFields = true
+	}
+}
+
+// WithCallerSkip adds the "_caller" field skipping skip frames, for loggers wrapped by a facade.
+func WithCallerSkip(skip int) Option {
+	return func(c *LoggingConfiguration) {
+		c.EnableCaller = true
+		c.CallerSkip = skip
+	}
+}
+
+// WithStacktrace adds the stack trace to error messages as "full_message".
+func WithStacktrace() Option {
+	return func(c *LoggingConfiguration) {
+		c.EnableStacktrace = true
+	}
+}
+
+// WithFieldPrefix prepends prefix to the non-reserved field keys of the GELF outputs, e.g. "app.".
+func WithFieldPrefix(prefix string) Option {
+	return func(c *LoggingConfiguration) {
+		c.FieldPrefix = prefix
+	}
+}
+
+// WithKeys overrides the keys of the entry fields, the empty keys keep the defaults.
+func WithKeys(keys KeyNames) Option {
+	return func(c *LoggingConfiguration) {
+		c.Keys = keys
+	}
+}
+
+// WithHostnameFunc resolves the GELF host field of each message with hostname.
+func WithHostnameFunc(hostname func() string) Option {
+	return func(c *LoggingConfiguration) {
+		c.HostnameFunc = hostname
+	}
+}
+
+// WithOmitFields leaves the injected pid or exe fields out of the messages.
+func WithOmitFields(keys ...string) Option {
+	return func(c *LoggingConfiguration) {
+		c.OmitFields = append(c.OmitFields, keys...)
+	}
+}
+
+// WithStructuredErrors sends zap.Error fields as the _error object and the _error_chain array.
+func WithStructuredErrors() Option {
+	return func(c *LoggingConfiguration) {
+		c.StructuredErrors = true
+	}
+}
+
+// WithStaticFields attaches fields to every message.
+func WithStaticFields(fields map[string]string) Option {
+	return func(c *LoggingConfiguration) {
+		c.StaticFields = fields
+	}
+}
+
+// WithAsync sends messages from a background goroutine through a queue of queueSize,
+// zero queueSize means DefaultQueueSize.
+func WithAsync(queueSize int) Option {
+	return func(c *LoggingConfiguration) {
+		c.Async = true
+		c.QueueSize = queueSize
+	}
+}
+
+// WithClock encodes the timestamps returned by clock, e.g. a fixed time in tests.
+func WithClock(clock func() time.Time) Option {
+	return func(c *LoggingConfiguration) {
+		c.Clock = clock
+	}
+}
+
+// WithKubernetes adds the pod metadata fields, see LoggingConfiguration.Kubernetes.
+func WithKubernetes() Option {
+	return func(c *LoggingConfiguration) {
+		c.Kubernetes = true
+	}
+}
+
+// WithJournald writes the entries to the systemd journal, see LoggingConfiguration.Journald.
+func WithJournald() Option {
+	return func(c *LoggingConfiguration) {
+		c.Journald = true
+	}
+}
+
+// WithOverflowPolicy sets how the async writer handles messages logged while its queue is full.
+func WithOverflowPolicy(policy OverflowPolicy) Option {
+	return func(c *LoggingConfiguration) {
+		c.OverflowPolicy = policy
+	}
+}
+
+// WithTLS connects to Graylog over TCP with TLS.
+func WithTLS(config *tls.Config) Option {
+	return func(c *LoggingConfiguration) {
+		c.Transport = TransportTCP
+		c.TLSConfig = config
+	}
+}
+
+// WithMaxMessageBytes drops messages longer than size bytes uncompressed,
+// or truncates them with WithTruncateOversized.
+func WithMaxMessageBytes(size int) Option {
+	return func(c *LoggingConfiguration) {
+		c.MaxMessageBytes = size
+	}
+}
+
+// WithTruncateOversized truncates UDP messages needing more than MaxChunkCount chunks instead of failing.
+func WithTruncateOversized() Option {
+	return func(c *LoggingConfiguration) {
+		c.TruncateOversized = true
+	}
+}
+
+// WithRawGELF sends uncompressed GELF JSON, bypassing the compressors. Use it when a Graylog input
+// misbehaves with a codec, e.g. older UDP inputs with zlib, or on a LAN where bandwidth doesn't matter.
+// It wins over WithCompression, whatever the order of the options.
+func WithRawGELF() Option {
+	return func(c *LoggingConfiguration) {
+		c.RawGELF = true
+	}
+}
+
+// WithCompressionThreshold sends messages shorter than size bytes uncompressed.
+func WithCompressionThreshold(size int) Option {
+	return func(c *LoggingConfiguration) {
+		c.CompressionThreshold = size
+	}
+}
+
+// WithSampling samples repeated Graylog entries below error level,
+// nil config uses DefaultSamplingInitial and DefaultSamplingThereafter.
+func WithSampling(config *zap.SamplingConfig) Option {
+	return func(c *LoggingConfiguration) {
+		if config == nil {
+			config = &zap.SamplingConfig{}
+		}
+
+		c.Sampling = config
+	}
+}
+
+// WithWriteRetries sets how many times failed stream writes are retried and the base backoff delay.
+func WithWriteRetries(retries int, delay time.Duration) Option {
+	return func(c *LoggingConfiguration) {
+		c.WriteRetries = retries
+		c.WriteRetryDelay = delay
+	}
+}
+
+// WithDialTimeout limits connecting to Graylog.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(c *LoggingConfiguration) {
+		c.DialTimeout = timeout
+	}
+}
+
+// WithWriteTimeout limits each TCP and unix write, a negative timeout disables the limit.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(c *LoggingConfiguration) {
+		c.WriteTimeout = timeout
+	}
+}
+
+// WithRedactKeys replaces the values of fields with the keys by "***",
+// withDefaults also redacts DefaultRedactKeys.
+func WithRedactKeys(withDefaults bool, keys ...string) Option {
+	return func(c *LoggingConfiguration) {
+		c.RedactKeys = append(c.RedactKeys, keys...)
+		c.RedactDefaults = c.RedactDefaults || withDefaults
+	}
+}
+
+// WithConsoleTimeEncoder sets the console timestamp encoding, e.g. zapcore.EpochTimeEncoder.
+func WithConsoleTimeEncoder(encoder zapcore.TimeEncoder) Option {
+	return func(c *LoggingConfiguration) {
+		c.ConsoleTimeEncoder = encoder
+	}
+}
+
+// WithFile adds a rotating file output, maxSize is in megabytes and maxAge in days.
+func WithFile(path string, maxSize, maxBackups, maxAge int) Option {
+	return func(c *LoggingConfiguration) {
+		c.FilePath = path
+		c.FileMaxSize = maxSize
+		c.FileMaxBackups = maxBackups
+		c.FileMaxAge = maxAge
+	}
+}
+
+// WithFacility sets the GELF facility field.
+func WithFacility(name string) Option {
+	return func(c *LoggingConfiguration) {
+		c.Facility = name
+	}
+}
+
+// WithSampler logs the first and every n-th entry of a message, the counters are cleared
+// every reset, zero reset means DefaultNthSamplingReset.
+func WithSampler(n int, reset time.Duration) Option {
+	return func(c *LoggingConfiguration) {
+		c.NthSampling = &NthSamplingConfig{N: n, Reset: reset}
+	}
+}
+
+// WithDedup limits identical entries to max per interval, zero values use the defaults.
+func WithDedup(max int, interval time.Duration) Option {
+	return func(c *LoggingConfiguration) {
+		c.Dedup = &DedupConfig{Max: max, Interval: interval}
+	}
+}
+
+// WithBatch buffers messages until they reach size bytes or interval has passed.
+func WithBatch(size int, interval time.Duration) Option {
+	return func(c *LoggingConfiguration) {
+		c.BatchSize = size
+		c.BatchInterval = interval
+	}
+}
+
+// WithSentry forwards entries at or above level to the Sentry project of dsn.
+func WithSentry(dsn string, level zapcore.Level) Option {
+	return func(c *LoggingConfiguration) {
+		c.Sentry = &SentryConfig{DSN: dsn, Level: level}
+	}
+}
+
+// WithOTel emits entries at or above level as OpenTelemetry log records to exporter.
+func WithOTel(exporter OTelExporter, level zapcore.Level) Option {
+	return func(c *LoggingConfiguration) {
+		c.OTel = &OTelConfig{Exporter: exporter, Level: level}
+	}
+}
+
+// WithSink writes the entries at or above min to ws in addition to the other outputs.
+func WithSink(min zapcore.Level, ws zapcore.WriteSyncer) Option {
+	return func(c *LoggingConfiguration) {
+		c.Sinks = append(c.Sinks, Sink{Level: min, Writer: ws})
+	}
+}
+
+// WithOnError calls onError on notable Graylog output events, it must not block.
+func WithOnError(onError func(event string, err error)) Option {
+	return func(c *LoggingConfiguration) {
+		c.OnError = onError
+	}
+}
+
+// WithOnDrop calls onDrop with the number of dropped messages, it must not block.
+func WithOnDrop(onDrop func(n int)) Option {
+	return func(c *LoggingConfiguration) {
+		c.OnDrop = onDrop
+	}
+}
+
+// WithOnSend calls onSend with the message ID and the chunk count of chunked messages, it must not block.
+func WithOnSend(onSend func(messageID []byte, chunks int)) Option {
+	return func(c *LoggingConfiguration) {
+		c.OnSend = onSend
+	}
+}
@@ -0,0 +1,168 @@
+package logger
+
+import (
+	"fmt"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// otelFlushTimeout is how long syncing waits for the exporter.
+const otelFlushTimeout = 5 * time.Second
+
+type (
+	// OTelConfig emits entries at or above Level as OpenTelemetry log records, next to the other outputs.
+	OTelConfig struct {
+		// Exporter receives the records, e.g. an adapter of an OTel SDK log.Logger
+		// whose processor exports to the OTLP endpoint.
+		Exporter OTelExporter
+
+		// Level is the minimal emitted level, zero means zapcore.InfoLevel.
+		Level zapcore.Level
+	}
+
+	// OTelExporter receives the log records. Emit must not block the logging goroutine,
+	// Flush waits for the pending records when the logger is synced.
+	// The package doesn't import the OTel SDK, so the exporter adapts OTelRecord to its log.Record:
+	//
+	//	func (e exporter) Emit(r *logger.OTelRecord) {
+	//		var rec log.Record
+	//		rec.SetTimestamp(r.Timestamp)
+	//		rec.SetSeverity(log.Severity(r.Severity))
+	//		rec.SetSeverityText(r.SeverityText)
+	//		rec.SetBody(log.StringValue(r.Body))
+	//		... // attributes and the span context parsed from r.TraceID and r.SpanID
+	//		e.logger.Emit(ctx, rec)
+	//	}
+	OTelExporter interface {
+		Emit(record *OTelRecord)
+		Flush(timeout time.Duration) bool
+	}
+
+	// OTelRecord is an entry in the OpenTelemetry log data model. The trace_id and span_id fields,
+	// added by WithContext from the active span, become TraceID and SpanID instead of attributes.
+	OTelRecord struct {
+		Timestamp    time.Time
+		Severity     int
+		SeverityText string
+		Body         string
+		Scope        string
+		TraceID      string
+		SpanID       string
+		Attributes   map[string]interface{}
+	}
+
+	// otelCore converts entries to OTel log records.
+	otelCore struct {
+		zapcore.LevelEnabler
+
+		fields   []zapcore.Field
+		exporter OTelExporter
+	}
+)
+
+// NewOTelCore creates the core emitting entries to config.Exporter,
+// e.g. to compose it with zapcore.NewTee outside of New which adds it for LoggingConfiguration.OTel.
+func NewOTelCore(config OTelConfig) (zapcore.Core, error) {
+	if config.Exporter == nil {
+		return nil, fmt.Errorf("otel exporter is not set")
+	}
+
+	return &otelCore{LevelEnabler: config.Level, exporter: config.Exporter}, nil
+}
+
+// With implements zapcore.Core.
+func (c *otelCore) With(fields []zapcore.Field) zapcore.Core {
+	var clone = *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *otelCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *otelCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+
+	var enc = zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	var record = &OTelRecord{
+		Timestamp:    ent.Time,
+		Severity:     otelSeverity(ent.Level),
+		SeverityText: ent.Level.CapitalString(),
+		Body:         ent.Message,
+		Scope:        ent.LoggerName,
+		Attributes:   enc.Fields,
+	}
+
+	if id, ok := enc.Fields["trace_id"].(string); ok {
+		record.TraceID = id
+		delete(enc.Fields, "trace_id")
+	}
+
+	if id, ok := enc.Fields["span_id"].(string); ok {
+		record.SpanID = id
+		delete(enc.Fields, "span_id")
+	}
+
+	if ent.Caller.Defined {
+		enc.Fields["code.filepath"] = ent.Caller.File
+		enc.Fields["code.lineno"] = ent.Caller.Line
+	}
+
+	if ent.Stack != "" {
+		enc.Fields["exception.stacktrace"] = ent.Stack
+	}
+
+	c.exporter.Emit(record)
+
+	return nil
+}
+
+// Sync implements zapcore.Core, it waits for the pending records.
+func (c *otelCore) Sync() error {
+	if !c.exporter.Flush(otelFlushTimeout) {
+		return fmt.Errorf("otel records are not exported after %s", otelFlushTimeout)
+	}
+
+	return nil
+}
+
+// otelSeverity maps zap levels to OTel severity numbers.
+func otelSeverity(l zapcore.Level) int {
+	switch l {
+	case zapcore.DebugLevel:
+		return 5 // DEBUG
+	case zapcore.InfoLevel:
+		return 9 // INFO
+	case zapcore.WarnLevel:
+		return 13 // WARN
+	case zapcore.ErrorLevel:
+		return 17 // ERROR
+	case zapcore.DPanicLevel:
+		return 19 // ERROR3
+	case zapcore.PanicLevel:
+		return 21 // FATAL
+	case zapcore.FatalLevel:
+		return 24 // FATAL4
+	default:
+		return 9
+	}
+}
@@ -0,0 +1,126 @@
+package logger_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"go.cantor.systems/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// memoryExporter keeps the records in memory.
+type memoryExporter struct {
+	mu      sync.Mutex
+	records []*logger.OTelRecord
+	flushed int
+}
+
+func (e *memoryExporter) Emit(record *logger.OTelRecord) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.records = append(e.records, record)
+}
+
+func (e *memoryExporter) Flush(time.Duration) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.flushed++
+
+	return true
+}
+
+func TestNewOTel(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+	exporter := &memoryExporter{}
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		AppName:        "test",
+		Transport:      logger.TransportTCP,
+		OTel:           &logger.OTelConfig{Exporter: exporter, Level: zapcore.WarnLevel},
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	defer func(extractor func(context.Context) (string, string)) { logger.TraceExtractor = extractor }(logger.TraceExtractor)
+	logger.TraceExtractor = func(context.Context) (string, string) {
+		return "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7"
+	}
+
+	ctx := logger.ContextWithLogger(context.Background(), log.Logger)
+
+	log.Info("not emitted")
+	logger.WithContext(ctx).Error("failed", zap.Int("attempts", 3))
+
+	// the Graylog path is unchanged
+	for _, expected := range []string{"not emitted", "failed"} {
+		if msg := <-messages; msg["short_message"] != expected {
+			t.Fatalf("unexpected Graylog message %v", msg)
+		}
+	}
+
+	if len(exporter.records) != 1 {
+		t.Fatalf("expected one record but got %d", len(exporter.records))
+	}
+
+	record := exporter.records[0]
+	if record.Body != "failed" || record.Severity != 17 || record.SeverityText != "ERROR" || record.Scope != "test" {
+		t.Fatalf("unexpected record %+v", record)
+	}
+
+	if record.TraceID != "4bf92f3577b34da6a3ce929d0e0e4736" || record.SpanID != "00f067aa0ba902b7" {
+		t.Fatalf("unexpected trace correlation %+v", record)
+	}
+
+	if _, ok := record.Attributes["trace_id"]; ok || record.Attributes["attempts"] != int64(3) || record.Attributes["app_name"] != "test" {
+		t.Fatalf("unexpected attributes %v", record.Attributes)
+	}
+
+	_ = log.Sync()
+
+	if exporter.flushed == 0 {
+		t.Fatal("exporter is not flushed on sync")
+	}
+}
+
+func TestNewOTelCore(t *testing.T) {
+	if _, err := logger.NewOTelCore(logger.OTelConfig{}); err == nil {
+		t.Fatal("expected error without exporter")
+	}
+
+	exporter := &memoryExporter{}
+
+	core, err := logger.NewOTelCore(logger.OTelConfig{Exporter: exporter})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	log := zap.New(core).With(zap.String("component", "worker"))
+	log.Debug("not emitted")
+	log.Info("started")
+	log.Warn("slow")
+
+	if len(exporter.records) != 2 {
+		t.Fatalf("expected two records but got %d", len(exporter.records))
+	}
+
+	if r := exporter.records[1]; r.Severity != 13 || r.TraceID != "" || r.Attributes["component"] != "worker" {
+		t.Fatalf("unexpected record %+v", r)
+	}
+
+	// direct writes, e.g. through a tee, are filtered too
+	if err = core.Write(zapcore.Entry{Level: zapcore.DebugLevel, Message: "written"}, nil); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if len(exporter.records) != 2 {
+		t.Fatalf("expected the debug write to be dropped but got %d records", len(exporter.records))
+	}
+}
@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var (
+	// pingCacheTTL is how long Ping returns the last result without checking again, replaced in tests.
+	pingCacheTTL = time.Second
+
+	// pingProbeTimeout is how long Ping waits for a closed connection or a refused heartbeat.
+	pingProbeTimeout = 50 * time.Millisecond
+
+	errNotConnected = errors.New("graylog is not connected")
+)
+
+// pinger caches the last Ping result of a writer.
+type pinger struct {
+	mu  sync.Mutex
+	at  time.Time
+	err error
+}
+
+// Ping checks that Graylog is reachable, e.g. for a readiness endpoint: it checks that the TCP or
+// unix connection isn't closed, reconnecting otherwise. Other transports get a heartbeat message,
+// posted to HTTP inputs, produced to Kafka and sent to UDP inputs, reporting the refused datagrams.
+// The result is cached for a second.
+// It returns an error when the logger fell back to stdout or has no Graylog output.
+func (l *Logger) Ping() error {
+	var w = l.gelf
+	if w == nil && l.replay != nil {
+		w = l.replay.writer()
+	}
+
+	if w == nil {
+		return errNotConnected
+	}
+
+	return w.ping()
+}
+
+// ping checks the connection, see Logger.Ping.
+func (w *writer) ping() error {
+	w.pinger.mu.Lock()
+	defer w.pinger.mu.Unlock()
+
+	if time.Since(w.pinger.at) < pingCacheTTL {
+		return w.pinger.err
+	}
+
+	w.pinger.err = w.check()
+	w.pinger.at = time.Now()
+
+	return w.pinger.err
+}
+
+// check checks the connection without caching.
+func (w *writer) check() error {
+	if w.transport == TransportHTTP {
+		_, err := w.post(w.heartbeat())
+		return err
+	}
+
+	if w.transport == TransportKafka {
+		_, err := w.produce(w.heartbeat())
+		return err
+	}
+
+	var conn = w.connection()
+
+	w.mu.Lock()
+	closed := w.closed
+	w.mu.Unlock()
+
+	if closed {
+		return errWriterClosed
+	}
+
+	if w.stream() {
+		if probe(conn) == nil {
+			return nil
+		}
+
+		return w.reconnect(conn)
+	}
+
+	w.writeMu.Lock()
+	_, err := w.sendTo(conn, w.heartbeat())
+	w.writeMu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	return probe(conn)
+}
+
+// heartbeat returns the GELF message sent to check UDP and HTTP inputs.
+func (w *writer) heartbeat() []byte {
+	return []byte(`{"version":"` + GELFVersion + `","host":` + strconv.Quote(w.hostname) +
+		`,"short_message":"heartbeat","level":7,"_heartbeat":true}`)
+}
+
+// probe reads from conn with a short deadline. Collectors never write, so a timeout means that
+// the connection is alive, while EOF or an error such as a refused UDP datagram that it is not.
+func probe(conn net.Conn) error {
+	_ = conn.SetReadDeadline(time.Now().Add(pingProbeTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var b [1]byte
+
+	_, err := conn.Read(b[:])
+	if ne, ok := err.(net.Error); err == nil || ok && ne.Timeout() {
+		return nil
+	}
+
+	return fmt.Errorf("graylog connection is down: %w", err)
+}
@@ -0,0 +1,131 @@
+package logger
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoggerPingTCP(t *testing.T) {
+	defer func(ttl time.Duration) { pingCacheTTL = ttl }(pingCacheTTL)
+	pingCacheTTL = 0
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			accepted <- conn
+		}
+	}()
+
+	log, err := New(LoggingConfiguration{GraylogAddress: ln.Addr().String(), Transport: TransportTCP})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	if err = log.Ping(); err != nil {
+		t.Fatal("live collector is not healthy:", err)
+	}
+
+	// the collector goes down
+	_ = ln.Close()
+	_ = (<-accepted).Close()
+
+	if err = log.Ping(); err == nil {
+		t.Fatal("expected error for a dead collector")
+	}
+}
+
+func TestLoggerPingUDP(t *testing.T) {
+	defer func(ttl time.Duration) { pingCacheTTL = ttl }(pingCacheTTL)
+	pingCacheTTL = 0
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	log, err := New(LoggingConfiguration{GraylogAddress: conn.LocalAddr().String(), Hostname: "probe"})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	if err = log.Ping(); err != nil {
+		t.Fatal("live collector is not healthy:", err)
+	}
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil || string(buf[:n]) != `{"version":"1.1","host":"probe","short_message":"heartbeat","level":7,"_heartbeat":true}` {
+		t.Fatalf("unexpected heartbeat %q: %v", buf[:n], err)
+	}
+
+	_ = conn.Close()
+
+	if err = log.Ping(); err == nil {
+		t.Fatal("expected error for a dead collector")
+	}
+}
+
+func TestLoggerPingHTTP(t *testing.T) {
+	defer func(ttl time.Duration) { pingCacheTTL = ttl }(pingCacheTTL)
+	pingCacheTTL = 0
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		rw.WriteHeader(http.StatusAccepted)
+	}))
+
+	log, err := New(LoggingConfiguration{GraylogAddress: srv.URL + "/gelf", HTTPClient: srv.Client()})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	if err = log.Ping(); err != nil {
+		t.Fatal("live collector is not healthy:", err)
+	}
+
+	srv.Close()
+
+	if err = log.Ping(); err == nil {
+		t.Fatal("expected error for a dead collector")
+	}
+}
+
+func TestLoggerPingCache(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	log, err := New(LoggingConfiguration{GraylogAddress: conn.LocalAddr().String()})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	if err = log.Ping(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	// the cached result is returned without sending another heartbeat
+	_ = conn.Close()
+
+	if err = log.Ping(); err != nil {
+		t.Fatal("expected the cached result but got:", err)
+	}
+
+	if err = (&Logger{}).Ping(); err != errNotConnected {
+		t.Fatal("expected not connected error but got:", err)
+	}
+}
@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"go.uber.org/zap/zapcore"
+)
+
+// prefixCore prepends a prefix to the keys of the fields which aren't reserved GELF or logger fields.
+type prefixCore struct {
+	zapcore.Core
+
+	prefix string
+}
+
+// newPrefixCore wraps core, prefixing the non-reserved field keys with prefix.
+func newPrefixCore(core zapcore.Core, prefix string) zapcore.Core {
+	return &prefixCore{Core: core, prefix: prefix}
+}
+
+// With implements zapcore.Core.
+func (c *prefixCore) With(fields []zapcore.Field) zapcore.Core {
+	return &prefixCore{Core: c.Core.With(c.prefixed(fields)), prefix: c.prefix}
+}
+
+// Check implements zapcore.Core.
+func (c *prefixCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *prefixCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.prefixed(fields))
+}
+
+// prefixed returns a copy of fields with the prefixed keys, the caller owns fields.
+func (c *prefixCore) prefixed(fields []zapcore.Field) []zapcore.Field {
+	var prefixed = make([]zapcore.Field, len(fields))
+	for i, f := range fields {
+		if !reservedFields[f.Key] {
+			f.Key = c.prefix + f.Key
+		}
+
+		prefixed[i] = f
+	}
+
+	return prefixed
+}
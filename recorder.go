@@ -0,0 +1,91 @@
+package logger
+
+import (
+	"bufio"
+	"errors"
+	"net"
+	"net/http"
+)
+
+// errNotHijacker is returned by Hijack when the wrapped writer doesn't support hijacking.
+var errNotHijacker = errors.New("response writer does not implement http.Hijacker")
+
+// ResponseRecorder is a http.ResponseWriter recording the status code and the body size
+// written by a handler, for access logging middleware. It forwards http.Flusher,
+// http.Hijacker and http.Pusher to the wrapped writer, so streaming handlers keep working.
+type ResponseRecorder struct {
+	http.ResponseWriter
+
+	// Status is the response status code, http.StatusOK when the handler never calls WriteHeader.
+	Status int
+
+	// Size is the number of body bytes written.
+	Size int64
+
+	// Hijacked reports whether the handler took over the connection, Status and Size are meaningless then.
+	Hijacked bool
+
+	wroteHeader bool
+}
+
+// NewResponseRecorder wraps w.
+func NewResponseRecorder(w http.ResponseWriter) *ResponseRecorder {
+	return &ResponseRecorder{ResponseWriter: w, Status: http.StatusOK}
+}
+
+// WriteHeader implements http.ResponseWriter.
+func (r *ResponseRecorder) WriteHeader(code int) {
+	if !r.wroteHeader {
+		r.Status = code
+		r.wroteHeader = true
+	}
+
+	r.ResponseWriter.WriteHeader(code)
+}
+
+// Write implements http.ResponseWriter.
+func (r *ResponseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+
+	n, err := r.ResponseWriter.Write(b)
+	r.Size += int64(n)
+
+	return n, err
+}
+
+// Flush implements http.Flusher, it is a no-op when the wrapped writer can't flush.
+func (r *ResponseRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		r.wroteHeader = true
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (r *ResponseRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := r.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errNotHijacker
+	}
+
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		r.Hijacked = true
+	}
+
+	return conn, rw, err
+}
+
+// Push implements http.Pusher.
+func (r *ResponseRecorder) Push(target string, opts *http.PushOptions) error {
+	if p, ok := r.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController.
+func (r *ResponseRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
@@ -0,0 +1,110 @@
+package logger_test
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.cantor.systems/logger"
+)
+
+func TestResponseRecorder(t *testing.T) {
+	for name, tc := range map[string]struct {
+		handler http.HandlerFunc
+		status  int
+		size    int64
+	}{
+		"implicit 200": {
+			handler: func(w http.ResponseWriter, r *http.Request) { _, _ = w.Write([]byte("hello")) },
+			status:  http.StatusOK,
+			size:    5,
+		},
+		"no body": {
+			handler: func(w http.ResponseWriter, r *http.Request) {},
+			status:  http.StatusOK,
+		},
+		"explicit": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusTeapot)
+				w.WriteHeader(http.StatusInternalServerError) // superfluous, ignored
+				_, _ = w.Write([]byte("tea"))
+			},
+			status: http.StatusTeapot,
+			size:   3,
+		},
+		"flushed": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.(http.Flusher).Flush()
+				w.WriteHeader(http.StatusNotFound) // too late
+			},
+			status: http.StatusOK,
+		},
+	} {
+		rec := logger.NewResponseRecorder(httptest.NewRecorder())
+		tc.handler(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		if rec.Status != tc.status || rec.Size != tc.size || rec.Hijacked {
+			t.Errorf("%s: unexpected status %d, size %d", name, rec.Status, rec.Size)
+		}
+	}
+}
+
+func TestResponseRecorderHijack(t *testing.T) {
+	var recorded = make(chan *logger.ResponseRecorder, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rec := logger.NewResponseRecorder(w)
+		defer func() { recorded <- rec }()
+
+		conn, rw, err := rec.Hijack()
+		if err != nil {
+			t.Error("error occurred:", err)
+			return
+		}
+		defer conn.Close()
+
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\n\r\n")
+		_ = rw.Flush()
+	}))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte("GET / HTTP/1.1\r\nHost: test\r\n\r\n")); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		t.Fatalf("unexpected status %d", resp.StatusCode)
+	}
+
+	if rec := <-recorded; !rec.Hijacked {
+		t.Fatal("hijack is not recorded")
+	}
+}
+
+func TestResponseRecorderUnsupported(t *testing.T) {
+	// a bare writer implementing none of the optional interfaces
+	rec := logger.NewResponseRecorder(struct{ http.ResponseWriter }{httptest.NewRecorder()})
+
+	rec.Flush()
+
+	if _, _, err := rec.Hijack(); err == nil || rec.Hijacked {
+		t.Fatal("expected hijack error")
+	}
+
+	if err := rec.Push("/style.css", nil); err != http.ErrNotSupported {
+		t.Fatalf("unexpected push error %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"fmt"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// Recoverer returns a middleware recovering handler panics: the panic is logged at error level
+// with the stack trace, the method, path, remote_addr and request_id fields, and the client gets a 500
+// unless the response was already started. http.ErrAbortHandler is re-panicked,
+// so net/http aborts the response silently as documented.
+//
+// Wrap it with NewRequestID to log the request id, and with the access loggers to log the 500.
+func Recoverer(log *zap.Logger) func(http.Handler) http.Handler {
+	return func(handler http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var rec = NewResponseRecorder(w)
+
+			defer func() {
+				p := recover()
+				if p == nil {
+					return
+				}
+
+				if p == http.ErrAbortHandler {
+					panic(p)
+				}
+
+				var fields = []zap.Field{
+					zap.String("panic", fmt.Sprint(p)),
+					zap.String("method", r.Method),
+					zap.String("path", r.URL.Path),
+					zap.String("remote_addr", r.RemoteAddr),
+					zap.Stack("stacktrace"),
+				}
+
+				if id := RequestIDFromContext(r.Context()); id != "" {
+					fields = append(fields, zap.String("request_id", id))
+				}
+
+				log.Error("http handler panic", fields...)
+
+				if !rec.wroteHeader && !rec.Hijacked {
+					http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+				}
+			}()
+
+			handler.ServeHTTP(rec, r)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package logger_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.cantor.systems/logger"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRecoverer(t *testing.T) {
+	log, logs := logger.NewObserver()
+
+	handler := logger.NewRequestID(logger.Recoverer(log)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("boom")
+	})), nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/items", nil)
+	req.Header.Set(logger.RequestIDHeader, "r1")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500 but got %d", rec.Code)
+	}
+
+	if logs.Len() != 1 {
+		t.Fatalf("expected one entry but got %d", logs.Len())
+	}
+
+	entry := logs.All()[0]
+	fields := entry.ContextMap()
+
+	for key, expected := range map[string]interface{}{
+		"panic":       "boom",
+		"method":      "POST",
+		"path":        "/items",
+		"remote_addr": req.RemoteAddr,
+		"request_id":  "r1",
+	} {
+		if fields[key] != expected {
+			t.Errorf("%s: expected %v but got %v", key, expected, fields[key])
+		}
+	}
+
+	if entry.Level != zapcore.ErrorLevel || !strings.Contains(fields["stacktrace"].(string), "TestRecoverer") {
+		t.Fatalf("unexpected entry %v", entry)
+	}
+}
+
+func TestRecovererStarted(t *testing.T) {
+	log, _ := logger.NewObserver()
+
+	handler := logger.Recoverer(log)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("boom")
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("started response is overwritten with %d", rec.Code)
+	}
+}
+
+func TestRecovererAbort(t *testing.T) {
+	log, logs := logger.NewObserver()
+
+	handler := logger.Recoverer(log)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	defer func() {
+		if p := recover(); p != http.ErrAbortHandler {
+			t.Fatalf("expected http.ErrAbortHandler but got %v", p)
+		}
+
+		if logs.Len() != 0 {
+			t.Fatalf("aborted request is logged: %v", logs.All())
+		}
+	}()
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+}
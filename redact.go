@@ -0,0 +1,175 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// redactedValue replaces the values of redacted fields.
+const redactedValue = "***"
+
+// DefaultRedactKeys are the keys redacted when RedactDefaults is set.
+var DefaultRedactKeys = []string{
+	"authorization",
+	"cookie",
+	"set-cookie",
+	"password",
+	"passwd",
+	"secret",
+	"token",
+	"access_token",
+	"refresh_token",
+	"api_key",
+	"apikey",
+}
+
+// redactCore replaces the values of sensitive fields before they are encoded.
+// It wraps a single output core, a tee would write to all of its cores without their level checks.
+type redactCore struct {
+	zapcore.Core
+
+	keys map[string]bool
+}
+
+// redactKeys returns the lower-cased keys to redact.
+func (c *LoggingConfiguration) redactKeys() map[string]bool {
+	var keys = make(map[string]bool, len(c.RedactKeys)+len(DefaultRedactKeys))
+	for _, key := range c.RedactKeys {
+		keys[strings.ToLower(key)] = true
+	}
+
+	if c.RedactDefaults {
+		for _, key := range DefaultRedactKeys {
+			keys[key] = true
+		}
+	}
+
+	return keys
+}
+
+// newRedactCore wraps core, redacting the fields with keys, which must be lower-cased.
+func newRedactCore(core zapcore.Core, keys map[string]bool) zapcore.Core {
+	return &redactCore{Core: core, keys: keys}
+}
+
+// With implements zapcore.Core.
+func (c *redactCore) With(fields []zapcore.Field) zapcore.Core {
+	return &redactCore{Core: c.Core.With(c.redact(fields)), keys: c.keys}
+}
+
+// Check implements zapcore.Core.
+func (c *redactCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+// Write implements zapcore.Core.
+func (c *redactCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	return c.Core.Write(ent, c.redact(fields))
+}
+
+// redact returns the fields with sensitive values replaced, fields are copied only when needed.
+func (c *redactCore) redact(fields []zapcore.Field) []zapcore.Field {
+	var redacted []zapcore.Field
+
+	for i, f := range fields {
+		rf, ok := c.redactField(f)
+		if !ok {
+			continue
+		}
+
+		if redacted == nil {
+			redacted = make([]zapcore.Field, len(fields))
+			copy(redacted, fields)
+		}
+
+		redacted[i] = rf
+	}
+
+	if redacted == nil {
+		return fields
+	}
+
+	return redacted
+}
+
+// redactField returns the redacted field and whether it changed.
+// Objects, arrays and reflected values are converted to plain maps and slices to be scrubbed.
+func (c *redactCore) redactField(f zapcore.Field) (zapcore.Field, bool) {
+	if c.keys[strings.ToLower(f.Key)] {
+		return zap.String(f.Key, redactedValue), true
+	}
+
+	var value interface{}
+
+	switch f.Type {
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType:
+		enc := zapcore.NewMapObjectEncoder()
+		f.AddTo(enc)
+		value = enc.Fields[f.Key]
+	case zapcore.StringerType:
+		body, ok := f.Interface.(capturedBody)
+		if !ok {
+			return f, false
+		}
+
+		redacted, ok := body.redacted(c.keys, c.scrub)
+		if !ok {
+			return f, false
+		}
+
+		return zap.String(f.Key, redacted), true
+	case zapcore.ReflectType:
+		buf, err := json.Marshal(f.Interface)
+		if err != nil {
+			return f, false
+		}
+
+		var decoder = json.NewDecoder(bytes.NewReader(buf))
+		decoder.UseNumber()
+
+		if decoder.Decode(&value) != nil {
+			return f, false
+		}
+	default:
+		return f, false
+	}
+
+	if !c.scrub(value) {
+		return f, false
+	}
+
+	return zap.Reflect(f.Key, value), true
+}
+
+// scrub redacts the sensitive keys of nested maps in place and reports whether any was found.
+func (c *redactCore) scrub(value interface{}) bool {
+	var found bool
+
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if c.keys[strings.ToLower(key)] {
+				v[key] = redactedValue
+				found = true
+			} else if c.scrub(nested) {
+				found = true
+			}
+		}
+	case []interface{}:
+		for _, nested := range v {
+			if c.scrub(nested) {
+				found = true
+			}
+		}
+	}
+
+	return found
+}
@@ -0,0 +1,57 @@
+package logger_test
+
+import (
+	"testing"
+
+	"go.cantor.systems/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type credentials struct {
+	User  string `json:"user"`
+	Token string `json:"token"`
+}
+
+func (c credentials) MarshalLogObject(enc zapcore.ObjectEncoder) error {
+	enc.AddString("user", c.User)
+	enc.AddString("api_key", c.Token)
+	return nil
+}
+
+func TestNewRedactKeys(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+		RedactKeys:     []string{"Session"},
+		RedactDefaults: true,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.With(zap.String("Authorization", "Bearer abc")).Info("redacted",
+		zap.String("session", "s3cr3t"),
+		zap.String("user", "alice"),
+		zap.Any("request", map[string]interface{}{"body": credentials{User: "bob", Token: "t0k3n"}}),
+		zap.Object("login", credentials{User: "carol", Token: "k3y"}),
+	)
+
+	msg := <-messages
+	if msg["Authorization"] != "***" || msg["session"] != "***" || msg["user"] != "alice" {
+		t.Fatalf("unexpected top-level fields: %v", msg)
+	}
+
+	body := msg["request"].(map[string]interface{})["body"].(map[string]interface{})
+	if body["token"] != "***" || body["user"] != "bob" {
+		t.Fatalf("unexpected reflected fields: %v", body)
+	}
+
+	login := msg["login"].(map[string]interface{})
+	if login["api_key"] != "***" || login["user"] != "carol" {
+		t.Fatalf("unexpected object fields: %v", login)
+	}
+}
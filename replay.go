@@ -0,0 +1,223 @@
+package logger
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultFallbackBufferBytes is default size limit of the fallback buffer.
+const DefaultFallbackBufferBytes = 1 << 20
+
+// replayRedialInterval is how often the fallback writer dials Graylog, replaced in tests.
+var replayRedialInterval = redialCooldown
+
+// replayWriter keeps the last messages while Graylog is unreachable at startup,
+// dials it in the background and replays them once it is connected.
+type replayWriter struct {
+	dropped   uint64 // accessed atomically, first for 64-bit alignment
+	connected int32  // accessed atomically, set once gelf is connected
+
+	configuration LoggingConfiguration
+	live          atomic.Value // the *writer once connected, read without mu by abort
+
+	mu       sync.Mutex // guards the fields below
+	pending  [][]byte
+	bytes    int
+	gelf     *writer
+	closed   bool
+	maxCount int
+	maxBytes int
+	done     chan struct{}
+}
+
+// newReplayWriter starts dialing the Graylog of configuration.
+func newReplayWriter(configuration LoggingConfiguration) *replayWriter {
+	var r = &replayWriter{
+		configuration: configuration,
+		maxCount:      configuration.FallbackBuffer,
+		maxBytes:      configuration.FallbackBufferBytes,
+		done:          make(chan struct{}),
+	}
+
+	go r.run()
+
+	return r
+}
+
+// run dials Graylog until it connects or the writer is closed.
+func (r *replayWriter) run() {
+	var ticker = time.NewTicker(replayRedialInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.done:
+			return
+		case <-ticker.C:
+		}
+
+		w, err := newWriter(r.configuration)
+		if err != nil {
+			continue
+		}
+
+		r.replay(w)
+
+		return
+	}
+}
+
+// replay sends the pending messages to w and makes it the destination of the next ones.
+func (r *replayWriter) replay(w *writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		_ = w.Close()
+		return
+	}
+
+	for _, msg := range r.pending {
+		// failures are counted by the writer stats
+		_, _ = w.Write(msg)
+	}
+
+	r.pending, r.bytes = nil, 0
+	r.gelf = w
+	r.live.Store(w)
+	atomic.StoreInt32(&r.connected, 1)
+}
+
+// Write implements io.Writer, messages are buffered until Graylog is connected,
+// dropping the oldest ones beyond the count and size limits.
+func (r *replayWriter) Write(buf []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return 0, errWriterClosed
+	}
+
+	if r.gelf != nil {
+		return r.gelf.Write(buf)
+	}
+
+	if len(buf) > r.maxBytes {
+		atomic.AddUint64(&r.dropped, 1)
+		return len(buf), nil
+	}
+
+	// copy the buffer, zap reuses it
+	r.pending = append(r.pending, append([]byte(nil), buf...))
+	r.bytes += len(buf)
+
+	for len(r.pending) > r.maxCount || r.bytes > r.maxBytes {
+		r.bytes -= len(r.pending[0])
+		r.pending[0] = nil
+		r.pending = r.pending[1:]
+		atomic.AddUint64(&r.dropped, 1)
+	}
+
+	return len(buf), nil
+}
+
+// writer returns the Graylog writer once connected, nil before.
+func (r *replayWriter) writer() *writer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.gelf
+}
+
+// Connected reports whether Graylog is connected.
+func (r *replayWriter) Connected() bool {
+	return atomic.LoadInt32(&r.connected) == 1
+}
+
+// Stats returns the counters of the Graylog writer once connected,
+// Dropped includes the messages evicted from the buffer.
+func (r *replayWriter) Stats() Stats {
+	var stats Stats
+
+	r.mu.Lock()
+	if r.gelf != nil {
+		stats = r.gelf.Stats()
+	}
+	r.mu.Unlock()
+
+	stats.Dropped += atomic.LoadUint64(&r.dropped)
+
+	return stats
+}
+
+// reportDrop calls the OnDrop and OnError callbacks for a message dropped because of err,
+// like the writer does once connected.
+func (r *replayWriter) reportDrop(err error) {
+	if r.configuration.OnDrop != nil {
+		r.configuration.OnDrop(1)
+	}
+
+	if r.configuration.OnError != nil {
+		r.configuration.OnError(EventDropped, err)
+	}
+}
+
+// abort closes the Graylog writer without waiting for mu, which the blocked writes hold.
+func (r *replayWriter) abort() {
+	if w, ok := r.live.Load().(*writer); ok {
+		_ = w.Close()
+	}
+}
+
+// Close stops dialing and closes the Graylog writer, buffered messages are lost.
+func (r *replayWriter) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return errWriterClosed
+	}
+
+	r.closed = true
+	close(r.done)
+
+	if r.gelf != nil {
+		return r.gelf.Close()
+	}
+
+	return nil
+}
+
+// fallbackCore is the console core used until the replay writer connects.
+type fallbackCore struct {
+	zapcore.Core
+
+	replay *replayWriter
+}
+
+// newFallbackCore disables core once replay is connected.
+func newFallbackCore(core zapcore.Core, replay *replayWriter) zapcore.Core {
+	return &fallbackCore{Core: core, replay: replay}
+}
+
+// Enabled implements zapcore.Core.
+func (c *fallbackCore) Enabled(l zapcore.Level) bool {
+	return !c.replay.Connected() && c.Core.Enabled(l)
+}
+
+// With implements zapcore.Core.
+func (c *fallbackCore) With(fields []zapcore.Field) zapcore.Core {
+	return &fallbackCore{Core: c.Core.With(fields), replay: c.replay}
+}
+
+// Check implements zapcore.Core.
+func (c *fallbackCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.replay.Connected() {
+		return ce
+	}
+
+	return c.Core.Check(ent, ce)
+}
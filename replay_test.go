@@ -0,0 +1,202 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewFallbackReplay(t *testing.T) {
+	defer func(interval time.Duration) { replayRedialInterval = interval }(replayRedialInterval)
+	replayRedialInterval = 10 * time.Millisecond
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	// the collector starts late on the address
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	log, err := New(LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      TransportTCP,
+		FallbackBuffer: 3,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	// the fallback warning and the first message are evicted
+	for i := 0; i < 4; i++ {
+		log.Info("early " + strconv.Itoa(i))
+	}
+
+	if ln, err = net.Listen("tcp", addr); err != nil {
+		t.Skip("collector port is reused:", err)
+	}
+	defer ln.Close()
+
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer conn.Close()
+
+	r := bufio.NewReader(conn)
+	read := func() string {
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+
+		frame, err := r.ReadBytes(0x00)
+		if err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		var msg map[string]interface{}
+		if err = json.Unmarshal(frame[:len(frame)-1], &msg); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		return msg["short_message"].(string)
+	}
+
+	for i := 1; i < 4; i++ {
+		if msg := read(); msg != "early "+strconv.Itoa(i) {
+			t.Fatalf("expected replayed early %d but got %q", i, msg)
+		}
+	}
+
+	for !log.replay.Connected() {
+		time.Sleep(time.Millisecond)
+	}
+
+	log.Info("connected")
+
+	if msg := read(); msg != "connected" {
+		t.Fatalf("unexpected message %q after replay", msg)
+	}
+
+	if stats := log.Stats(); stats.Dropped != 2 || stats.Sent != 4 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+}
+
+func TestLoggerDegraded(t *testing.T) {
+	defer func(interval time.Duration) { replayRedialInterval = interval }(replayRedialInterval)
+	replayRedialInterval = 10 * time.Millisecond
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	down, err := New(LoggingConfiguration{GraylogAddress: addr, Transport: TransportTCP})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer down.Close()
+
+	var graylogErr *GraylogError
+	if !down.Degraded() || !errors.As(down.DegradedReason(), &graylogErr) || graylogErr.Address != addr {
+		t.Fatalf("expected degraded logger but got reason %v", down.DegradedReason())
+	}
+
+	log, err := New(LoggingConfiguration{GraylogAddress: addr, Transport: TransportTCP, FallbackBuffer: 1})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	if !log.Degraded() {
+		t.Fatal("expected degraded logger before the collector starts")
+	}
+
+	if ln, err = net.Listen("tcp", addr); err != nil {
+		t.Skip("collector port is reused:", err)
+	}
+	defer ln.Close()
+
+	go func() {
+		if conn, err := ln.Accept(); err == nil {
+			defer conn.Close()
+			_, _ = ioutil.ReadAll(conn)
+		}
+	}()
+
+	for deadline := time.Now().Add(5 * time.Second); log.Degraded(); time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("logger didn't recover after the collector started")
+		}
+	}
+
+	if log.DegradedReason() != nil {
+		t.Fatal("unexpected reason for recovered logger:", log.DegradedReason())
+	}
+}
+
+func TestNewFallbackReplayAsyncDrops(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	addr := ln.Addr().String()
+	_ = ln.Close()
+
+	var dropped int32
+
+	log, err := New(LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      TransportTCP,
+		FallbackBuffer: 10,
+		Async:          true,
+		QueueSize:      1,
+		OnDrop:         func(n int) { atomic.AddInt32(&dropped, int32(n)) },
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	// the replay writer blocks the async goroutine, so the queue overflows
+	log.replay.mu.Lock()
+	for i := 0; i < 3; i++ {
+		_, _ = log.async.Write([]byte("queued"))
+	}
+	log.replay.mu.Unlock()
+
+	if n := atomic.LoadInt32(&dropped); n == 0 || uint64(n) != log.async.Dropped() {
+		t.Fatalf("expected the %d drops to be reported but got %d", log.async.Dropped(), n)
+	}
+}
+
+func TestReplayWriterLimits(t *testing.T) {
+	r := &replayWriter{maxCount: 10, maxBytes: 10, done: make(chan struct{})}
+
+	for _, msg := range []string{"12345", "6789", "0", "too long message"} {
+		if _, err := r.Write([]byte(msg)); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+	}
+
+	if r.bytes != 10 || len(r.pending) != 3 {
+		t.Fatalf("unexpected %d pending messages of %d bytes", len(r.pending), r.bytes)
+	}
+
+	_, _ = r.Write([]byte("ab"))
+
+	if string(r.pending[0]) != "6789" || r.bytes != 7 || r.Stats().Dropped != 2 {
+		t.Fatalf("unexpected pending messages %q, stats %+v", r.pending, r.Stats())
+	}
+}
@@ -0,0 +1,60 @@
+package logger
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader is the header carrying the request id.
+const RequestIDHeader = "X-Request-Id"
+
+// requestIDKey is the context key of the request id.
+type requestIDKey struct{}
+
+// NewRequestID wraps handler with a middleware reading the request id from the X-Request-Id header,
+// or generating one when it is absent. The id is set on the response header and stored in the
+// request context, see RequestIDFromContext, and the logger stashed by ContextWithLogger gets
+// a request_id field, so application logs through WithContext carry it.
+//
+// Wrap the access loggers with it, e.g. NewRequestID(NewAccessLogJSON(mux, log), nil), so the access
+// entries carry the id too, apache formats can use %{X-Request-Id}o.
+// A nil generate defaults to 16 random bytes in hex.
+func NewRequestID(handler http.Handler, generate func() string) http.Handler {
+	if generate == nil {
+		generate = randomRequestID
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var id = r.Header.Get(RequestIDHeader)
+		if id == "" {
+			id = generate()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+
+		var ctx = context.WithValue(r.Context(), requestIDKey{}, id)
+		if log, ok := loggerFromContext(ctx); ok {
+			ctx = ContextWithLogger(ctx, log.With(zap.String("request_id", id)))
+		}
+
+		handler.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequestIDFromContext returns the request id stored by NewRequestID, empty when there is none.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// randomRequestID returns 16 random bytes in hex.
+func randomRequestID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:]) // crypto/rand doesn't fail on supported platforms
+
+	return hex.EncodeToString(id[:])
+}
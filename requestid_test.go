@@ -0,0 +1,70 @@
+package logger_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+
+	"go.cantor.systems/logger"
+)
+
+func TestNewRequestID(t *testing.T) {
+	log, logs := logger.NewObserver()
+
+	var seen string
+
+	handler := logger.NewRequestID(logger.NewAccessLogJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen = logger.RequestIDFromContext(r.Context())
+		logger.WithContext(r.Context()).Info("handled")
+	}), log), nil)
+
+	serve := func(id string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if id != "" {
+			req.Header.Set(logger.RequestIDHeader, id)
+		}
+
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req.WithContext(logger.ContextWithLogger(req.Context(), log)))
+
+		return rec
+	}
+
+	// generated
+	rec := serve("")
+	if !regexp.MustCompile(`^[0-9a-f]{32}$`).MatchString(seen) || rec.Header().Get(logger.RequestIDHeader) != seen {
+		t.Fatalf("unexpected generated id %q, response header %q", seen, rec.Header().Get(logger.RequestIDHeader))
+	}
+
+	// passed through
+	if rec = serve("upstream-id"); seen != "upstream-id" || rec.Header().Get(logger.RequestIDHeader) != "upstream-id" {
+		t.Fatalf("id is not passed through: %q", seen)
+	}
+
+	entries := logs.All()
+	if len(entries) != 4 {
+		t.Fatalf("expected app and access entries for both requests but got %d", len(entries))
+	}
+
+	for _, entry := range entries[2:] {
+		if entry.ContextMap()["request_id"] != "upstream-id" {
+			t.Fatalf("entry %q has no request_id: %v", entry.Message, entry.ContextMap())
+		}
+	}
+}
+
+func TestNewRequestIDGenerator(t *testing.T) {
+	handler := logger.NewRequestID(http.NotFoundHandler(), func() string { return "fixed" })
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	if id := rec.Header().Get(logger.RequestIDHeader); id != "fixed" {
+		t.Fatalf("unexpected id %q", id)
+	}
+
+	if id := logger.RequestIDFromContext(httptest.NewRequest(http.MethodGet, "/", nil).Context()); id != "" {
+		t.Fatalf("unexpected id %q without middleware", id)
+	}
+}
@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DefaultSamplingInitial and DefaultSamplingThereafter are used for unset SamplingConfig fields:
+// the first 100 entries with the same level and message each second are logged, then every 100th.
+const (
+	DefaultSamplingInitial    = 100
+	DefaultSamplingThereafter = 100
+)
+
+// samplerCore samples entries below error level, errors always pass.
+type samplerCore struct {
+	zapcore.Core
+
+	sampled zapcore.Core
+}
+
+// newSamplerCore wraps core with a per second sampler configured by config.
+// Sampling trades completeness for volume: repeated entries in a burst are dropped,
+// so counts derived from logs are no longer exact, but errors are never dropped.
+func newSamplerCore(core zapcore.Core, config zap.SamplingConfig) zapcore.Core {
+	if config.Initial == 0 {
+		config.Initial = DefaultSamplingInitial
+	}
+
+	if config.Thereafter == 0 {
+		config.Thereafter = DefaultSamplingThereafter
+	}
+
+	var opts []zapcore.SamplerOption
+	if config.Hook != nil {
+		opts = append(opts, zapcore.SamplerHook(config.Hook))
+	}
+
+	return &samplerCore{
+		Core:    core,
+		sampled: zapcore.NewSamplerWithOptions(core, time.Second, config.Initial, config.Thereafter, opts...),
+	}
+}
+
+// With implements zapcore.Core.
+func (c *samplerCore) With(fields []zapcore.Field) zapcore.Core {
+	return &samplerCore{
+		Core:    c.Core.With(fields),
+		sampled: c.sampled.With(fields),
+	}
+}
+
+// Check implements zapcore.Core.
+func (c *samplerCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if ent.Level >= zapcore.ErrorLevel {
+		return c.Core.Check(ent, ce)
+	}
+
+	return c.sampled.Check(ent, ce)
+}
@@ -0,0 +1,31 @@
+package logger
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestSamplerCore(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	log := zap.New(newSamplerCore(core, zap.SamplingConfig{Initial: 2, Thereafter: 5})).With(zap.String("k", "v"))
+
+	for i := 0; i < 10; i++ {
+		log.Info("repeated")
+		log.Error("failed")
+	}
+
+	if n := logs.FilterMessage("repeated").Len(); n != 3 {
+		t.Fatalf("expected 3 sampled info entries (1st, 2nd, 7th) but got %d", n)
+	}
+
+	if n := logs.FilterMessage("failed").Len(); n != 10 {
+		t.Fatalf("expected all 10 error entries but got %d", n)
+	}
+
+	if logs.All()[0].ContextMap()["k"] != "v" {
+		t.Fatal("fields are lost")
+	}
+}
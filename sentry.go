@@ -0,0 +1,293 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	// sentryMaxInFlight is how many events the HTTP transport sends concurrently, more are dropped.
+	sentryMaxInFlight = 16
+
+	// sentryMaxTagLength is the Sentry tag value limit, longer strings are sent as extra data.
+	sentryMaxTagLength = 200
+
+	// sentryFlushTimeout is how long syncing waits for the pending events.
+	sentryFlushTimeout = 5 * time.Second
+)
+
+type (
+	// SentryConfig forwards the entries enabled by Level to Sentry, next to the other outputs.
+	SentryConfig struct {
+		// DSN is the Sentry project DSN, e.g. https://<key>@o1.ingest.sentry.io/<project>.
+		DSN string
+
+		// Level enables the forwarded entries, e.g. zapcore.WarnLevel, nil means zapcore.ErrorLevel.
+		Level zapcore.LevelEnabler
+
+		// Transport sends the events, it defaults to a client of the Sentry store endpoint of DSN.
+		// Implement it to reuse the sentry-go client instead.
+		Transport SentryTransport
+	}
+
+	// SentryTransport sends events to Sentry. Send must not block the logging goroutine,
+	// Flush waits for the pending events when the logger is synced.
+	SentryTransport interface {
+		Send(event *SentryEvent)
+		Flush(timeout time.Duration) bool
+	}
+
+	// SentryEvent is a Sentry event in the store API format. Short string fields of the entry
+	// become tags, e.g. app_name and host, the other ones extra data.
+	SentryEvent struct {
+		EventID    string                 `json:"event_id"`
+		Timestamp  time.Time              `json:"timestamp"`
+		Level      string                 `json:"level"`
+		Logger     string                 `json:"logger,omitempty"`
+		Platform   string                 `json:"platform"`
+		Message    string                 `json:"message"`
+		ServerName string                 `json:"server_name,omitempty"`
+		Culprit    string                 `json:"culprit,omitempty"`
+		Tags       map[string]string      `json:"tags,omitempty"`
+		Extra      map[string]interface{} `json:"extra,omitempty"`
+	}
+
+	// sentryCore converts entries to Sentry events.
+	sentryCore struct {
+		zapcore.LevelEnabler
+
+		fields    []zapcore.Field
+		transport SentryTransport
+	}
+
+	// sentryHTTPTransport posts events to the store endpoint of a DSN.
+	sentryHTTPTransport struct {
+		client   *http.Client
+		endpoint string
+		auth     string
+
+		inFlight chan struct{}
+		wg       sync.WaitGroup
+	}
+)
+
+// newSentryCore creates the Sentry core of config.
+func newSentryCore(config SentryConfig) (zapcore.Core, error) {
+	if config.Level == nil {
+		config.Level = zapcore.ErrorLevel
+	}
+
+	if config.Transport == nil {
+		t, err := newSentryHTTPTransport(config.DSN)
+		if err != nil {
+			return nil, err
+		}
+
+		config.Transport = t
+	}
+
+	return &sentryCore{LevelEnabler: config.Level, transport: config.Transport}, nil
+}
+
+// With implements zapcore.Core.
+func (c *sentryCore) With(fields []zapcore.Field) zapcore.Core {
+	var clone = *c
+	clone.fields = append(c.fields[:len(c.fields):len(c.fields)], fields...)
+
+	return &clone
+}
+
+// Check implements zapcore.Core.
+func (c *sentryCore) Check(ent zapcore.Entry, ce *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(ent.Level) {
+		return ce.AddCore(ent, c)
+	}
+
+	return ce
+}
+
+// Write implements zapcore.Core, entries below the level are ignored
+// in case the core is written without checking it first.
+func (c *sentryCore) Write(ent zapcore.Entry, fields []zapcore.Field) error {
+	if !c.Enabled(ent.Level) {
+		return nil
+	}
+
+	var enc = zapcore.NewMapObjectEncoder()
+	for _, f := range c.fields {
+		f.AddTo(enc)
+	}
+
+	for _, f := range fields {
+		f.AddTo(enc)
+	}
+
+	var event = &SentryEvent{
+		EventID:   newEventID(),
+		Timestamp: ent.Time.UTC(),
+		Level:     sentryLevel(ent.Level),
+		Logger:    ent.LoggerName,
+		Platform:  "go",
+		Message:   ent.Message,
+		Tags:      make(map[string]string),
+		Extra:     make(map[string]interface{}),
+	}
+
+	if ent.Caller.Defined {
+		event.Culprit = ent.Caller.TrimmedPath()
+	}
+
+	if ent.Stack != "" {
+		event.Extra["stacktrace"] = ent.Stack
+	}
+
+	for key, value := range enc.Fields {
+		if s, ok := value.(string); ok && len(s) <= sentryMaxTagLength {
+			event.Tags[key] = s
+		} else {
+			event.Extra[key] = value
+		}
+	}
+
+	event.ServerName = event.Tags["host"]
+
+	c.transport.Send(event)
+
+	return nil
+}
+
+// Sync implements zapcore.Core, it waits for the pending events.
+func (c *sentryCore) Sync() error {
+	if !c.transport.Flush(sentryFlushTimeout) {
+		return fmt.Errorf("sentry events are not sent after %s", sentryFlushTimeout)
+	}
+
+	return nil
+}
+
+// sentryLevel maps zap levels to Sentry levels.
+func sentryLevel(l zapcore.Level) string {
+	switch {
+	case l <= zapcore.DebugLevel:
+		return "debug"
+	case l == zapcore.InfoLevel:
+		return "info"
+	case l == zapcore.WarnLevel:
+		return "warning"
+	case l == zapcore.ErrorLevel:
+		return "error"
+	default:
+		return "fatal"
+	}
+}
+
+// newEventID returns a random Sentry event id, 32 hex characters.
+func newEventID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+
+	return hex.EncodeToString(id[:])
+}
+
+// newSentryHTTPTransport creates the transport of the dsn store endpoint.
+func newSentryHTTPTransport(dsn string) (*sentryHTTPTransport, error) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.User == nil || u.Host == "" {
+		return nil, fmt.Errorf("invalid sentry DSN %q", dsn)
+	}
+
+	var (
+		path    = strings.TrimSuffix(u.Path, "/")
+		project = path[strings.LastIndex(path, "/")+1:]
+	)
+
+	if project == "" {
+		return nil, fmt.Errorf("sentry DSN %q has no project", dsn)
+	}
+
+	var auth = "Sentry sentry_version=7, sentry_client=go.cantor.systems/logger, sentry_key=" + u.User.Username()
+	if secret, ok := u.User.Password(); ok {
+		auth += ", sentry_secret=" + secret
+	}
+
+	return &sentryHTTPTransport{
+		client:   &http.Client{Timeout: 10 * time.Second},
+		endpoint: fmt.Sprintf("%s://%s%s/api/%s/store/", u.Scheme, u.Host, path[:len(path)-len(project)-1], project),
+		auth:     auth,
+		inFlight: make(chan struct{}, sentryMaxInFlight),
+	}, nil
+}
+
+// Send implements SentryTransport, events are dropped when too many are in flight.
+func (t *sentryHTTPTransport) Send(event *SentryEvent) {
+	select {
+	case t.inFlight <- struct{}{}:
+	default:
+		return
+	}
+
+	t.wg.Add(1)
+
+	go func() {
+		defer func() {
+			<-t.inFlight
+			t.wg.Done()
+		}()
+
+		_ = t.post(event)
+	}()
+}
+
+// Flush implements SentryTransport.
+func (t *sentryHTTPTransport) Flush(timeout time.Duration) bool {
+	var done = make(chan struct{})
+
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// post sends the event.
+func (t *sentryHTTPTransport) post(event *SentryEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Sentry-Auth", t.auth)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+
+	return resp.Body.Close()
+}
@@ -0,0 +1,169 @@
+package logger_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.cantor.systems/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// mockSentry records the events and the flushes.
+type mockSentry struct {
+	mu      sync.Mutex
+	events  []*logger.SentryEvent
+	flushed int
+}
+
+func (m *mockSentry) Send(event *logger.SentryEvent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.events = append(m.events, event)
+}
+
+func (m *mockSentry) Flush(time.Duration) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.flushed++
+
+	return true
+}
+
+func TestNewSentry(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+	sentry := &mockSentry{}
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		GraylogAddress:   addr,
+		AppName:          "test",
+		Transport:        logger.TransportTCP,
+		EnableStacktrace: true,
+		Sentry:           &logger.SentryConfig{Transport: sentry},
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Warn("not forwarded")
+	log.With(zap.String("request_id", "r1")).Error("failed", zap.Int("attempts", 3))
+
+	// the Graylog path is unchanged
+	for _, expected := range []string{"not forwarded", "failed"} {
+		if msg := <-messages; msg["short_message"] != expected {
+			t.Fatalf("unexpected Graylog message %v", msg)
+		}
+	}
+
+	if len(sentry.events) != 1 {
+		t.Fatalf("expected one event but got %d", len(sentry.events))
+	}
+
+	event := sentry.events[0]
+	if event.Message != "failed" || event.Level != "error" || event.Logger != "test" || len(event.EventID) != 32 {
+		t.Fatalf("unexpected event %+v", event)
+	}
+
+	if event.Tags["request_id"] != "r1" || event.Tags["app_name"] != "test" || event.Extra["attempts"] != int64(3) {
+		t.Fatalf("unexpected tags %v and extra %v", event.Tags, event.Extra)
+	}
+
+	if !strings.Contains(event.Extra["stacktrace"].(string), "TestNewSentry") {
+		t.Fatalf("unexpected stacktrace %v", event.Extra["stacktrace"])
+	}
+}
+
+func TestNewSentryDSN(t *testing.T) {
+	type request struct {
+		path, auth string
+		event      logger.SentryEvent
+	}
+
+	var requests = make(chan request, 1)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		var req = request{path: r.URL.Path, auth: r.Header.Get("X-Sentry-Auth")}
+		_ = json.NewDecoder(r.Body).Decode(&req.event)
+		requests <- req
+	}))
+	defer srv.Close()
+
+	dsn := strings.Replace(srv.URL, "http://", "http://public@", 1) + "/42"
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		Sentry: &logger.SentryConfig{DSN: dsn, Level: zapcore.WarnLevel},
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	log.Warn("warned")
+	_ = log.Sync()
+
+	req := <-requests
+	if req.path != "/api/42/store/" || !strings.Contains(req.auth, "sentry_key=public") {
+		t.Fatalf("unexpected request %s with auth %q", req.path, req.auth)
+	}
+
+	if req.event.Message != "warned" || req.event.Level != "warning" || req.event.Platform != "go" {
+		t.Fatalf("unexpected event %+v", req.event)
+	}
+
+	if _, err = logger.New(logger.LoggingConfiguration{Sentry: &logger.SentryConfig{DSN: "https://o1.ingest.sentry.io"}}); err == nil {
+		t.Fatal("expected error for invalid DSN")
+	}
+}
+
+func TestNewSentryInfoLevel(t *testing.T) {
+	sentry := &mockSentry{}
+
+	log, err := logger.New(logger.LoggingConfiguration{
+		Level:  "debug",
+		Sentry: &logger.SentryConfig{Transport: sentry, Level: zapcore.InfoLevel},
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Debug("not forwarded")
+	log.Info("forwarded")
+
+	sentry.mu.Lock()
+	defer sentry.mu.Unlock()
+
+	if len(sentry.events) != 1 || sentry.events[0].Message != "forwarded" || sentry.events[0].Level != "info" {
+		t.Fatalf("expected the info event only but got %+v", sentry.events)
+	}
+}
+
+func TestLoggerCloseFlushesSentry(t *testing.T) {
+	sentry := &mockSentry{}
+
+	// no Graylog, file or journald, the logger writes to stdout only
+	log, err := logger.New(logger.LoggingConfiguration{Sentry: &logger.SentryConfig{Transport: sentry}})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	log.Error("failed")
+
+	if err = log.Close(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	sentry.mu.Lock()
+	defer sentry.mu.Unlock()
+
+	if len(sentry.events) != 1 || sentry.flushed == 0 {
+		t.Fatalf("expected a flushed event but got %d events, %d flushes", len(sentry.events), sentry.flushed)
+	}
+}
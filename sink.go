@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"fmt"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Sink is an extra output receiving the GELF JSON entries at or above Level,
+// e.g. stderr or a Slack webhook writer for the errors while everything goes to Graylog.
+type Sink struct {
+	// Level is the minimal level written to the sink.
+	Level zapcore.Level
+
+	// Writer receives the encoded entries, one per write.
+	Writer zapcore.WriteSyncer
+}
+
+// newSinkCores creates a core per sink, enabled when both the sink level and the logger level are,
+// so the levels are evaluated per core and SetLevel still applies.
+func newSinkCores(sinks []Sink, encoder zapcore.Encoder, level zap.AtomicLevel) ([]zapcore.Core, error) {
+	var cores = make([]zapcore.Core, 0, len(sinks))
+	for i, sink := range sinks {
+		if sink.Writer == nil {
+			return nil, fmt.Errorf("writer of sink %d is not set", i)
+		}
+
+		var min = sink.Level
+		cores = append(cores, zapcore.NewCore(encoder.Clone(), sink.Writer, zap.LevelEnablerFunc(func(l zapcore.Level) bool {
+			return l >= min && level.Enabled(l)
+		})))
+	}
+
+	return cores, nil
+}
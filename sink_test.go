@@ -0,0 +1,89 @@
+package logger_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go.cantor.systems/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// sinkMessages decodes the GELF messages written to a sink.
+func sinkMessages(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var messages []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+
+		var msg map[string]interface{}
+		if err := json.Unmarshal([]byte(line), &msg); err != nil {
+			t.Fatalf("invalid message %q: %v", line, err)
+		}
+
+		messages = append(messages, msg)
+	}
+
+	return messages
+}
+
+func TestNewSinks(t *testing.T) {
+	var infos, errs bytes.Buffer
+
+	log, err := logger.NewWithOptions(
+		logger.WithSink(zapcore.InfoLevel, zapcore.AddSync(&infos)),
+		logger.WithSink(zapcore.ErrorLevel, zapcore.AddSync(&errs)),
+	)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Debug("below the logger level")
+	log.Info("info")
+	log.Error("error")
+
+	if messages := sinkMessages(t, &infos); len(messages) != 2 ||
+		messages[0]["short_message"] != "info" || messages[1]["short_message"] != "error" {
+		t.Fatalf("unexpected info sink messages: %v", messages)
+	}
+
+	messages := sinkMessages(t, &errs)
+	if len(messages) != 1 || messages[0]["short_message"] != "error" {
+		t.Fatalf("unexpected error sink messages: %v", messages)
+	}
+
+	if messages[0]["level"] != float64(3) {
+		t.Fatalf("unexpected level %v", messages[0]["level"])
+	}
+}
+
+func TestNewSinkWriter(t *testing.T) {
+	if _, err := logger.New(logger.LoggingConfiguration{Sinks: []logger.Sink{{Level: zapcore.ErrorLevel}}}); err == nil {
+		t.Fatal("expected error for a sink without writer")
+	}
+}
+
+func TestNewSinksRedacted(t *testing.T) {
+	var errs bytes.Buffer
+
+	log, err := logger.NewWithOptions(
+		logger.WithRedactKeys(false, "password"),
+		logger.WithSink(zapcore.ErrorLevel, zapcore.AddSync(&errs)),
+	)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("info", zap.String("password", "secret"))
+	log.Error("error", zap.String("password", "secret"))
+
+	if messages := sinkMessages(t, &errs); len(messages) != 1 ||
+		messages[0]["short_message"] != "error" || messages[0]["password"] != "***" {
+		t.Fatalf("unexpected error sink messages: %v", messages)
+	}
+}
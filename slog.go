@@ -0,0 +1,211 @@
+//go:build go1.21
+// +build go1.21
+
+package logger
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+)
+
+// SlogHandler is a slog.Handler writing GELF messages to Graylog.
+type SlogHandler struct {
+	level  slog.Leveler
+	out    io.Writer
+	gelf   *writer
+	mu     *sync.Mutex // serializes writes to out when it is not the GELF writer
+	fields map[string]interface{}
+	prefix string
+}
+
+// NewSlogHandler creates a slog.Handler sending records to Graylog with the same
+// writer, chunking and fields as New. Attributes become underscore-prefixed GELF
+// additional fields, groups are joined with underscores, e.g. `_request_id`.
+// The handler falls back to stderr like New, use Close to close the Graylog connection.
+func NewSlogHandler(configuration LoggingConfiguration) (*SlogHandler, error) {
+	if err := configuration.normalize(); err != nil {
+		return nil, err
+	}
+
+	if _, err := buildStaticFields(configuration.StaticFields); err != nil {
+		return nil, err
+	}
+
+	var level slog.Level
+	if configuration.Level != "" {
+		if err := level.UnmarshalText([]byte(configuration.Level)); err != nil {
+			return nil, err
+		}
+	}
+
+	var h = &SlogHandler{
+		level: level,
+		out:   os.Stderr,
+		mu:    new(sync.Mutex),
+		fields: map[string]interface{}{
+			"pid":      os.Getpid(),
+			"app_name": configuration.AppName,
+			"host":     configuration.Hostname,
+			"exe":      executableName(os.Args),
+			"version":  GELFVersion,
+		},
+	}
+
+	for key, value := range configuration.StaticFields {
+		h.fields[key] = value
+	}
+
+	if configuration.GraylogAddress != "" {
+		w, err := newWriter(configuration)
+		if err != nil && configuration.FailOnGraylogError {
+			return nil, err
+		}
+
+		if err == nil {
+			h.out, h.gelf = w, w
+		}
+	}
+
+	return h, nil
+}
+
+// Enabled implements slog.Handler.
+func (h *SlogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+// Handle implements slog.Handler.
+func (h *SlogHandler) Handle(_ context.Context, r slog.Record) error {
+	var msg = make(map[string]interface{}, len(h.fields)+r.NumAttrs()+4)
+	for key, value := range h.fields {
+		msg[key] = value
+	}
+
+	msg["short_message"] = r.Message
+	msg["level"] = syslogLevel(r.Level)
+	msg["level_name"] = r.Level.String()
+
+	if !r.Time.IsZero() {
+		msg["timestamp"] = float64(r.Time.UnixNano()) / float64(time.Second)
+	}
+
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(msg, h.prefix, a)
+		return true
+	})
+
+	buf, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+
+	buf = append(buf, '\n')
+
+	if h.gelf == nil {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+	}
+
+	_, err = h.out.Write(buf)
+
+	return err
+}
+
+// WithAttrs implements slog.Handler.
+func (h *SlogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	var clone = *h
+
+	clone.fields = make(map[string]interface{}, len(h.fields)+len(attrs))
+	for key, value := range h.fields {
+		clone.fields[key] = value
+	}
+
+	for _, a := range attrs {
+		addAttr(clone.fields, h.prefix, a)
+	}
+
+	return &clone
+}
+
+// WithGroup implements slog.Handler.
+func (h *SlogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	var clone = *h
+	clone.prefix = h.prefix + name + "_"
+
+	return &clone
+}
+
+// Close closes the Graylog connection, handlers derived with WithAttrs and WithGroup share it.
+// It is a no-op when the handler fell back to stderr.
+func (h *SlogHandler) Close() error {
+	if h.gelf == nil {
+		return nil
+	}
+
+	return h.gelf.Close()
+}
+
+// addAttr adds the resolved attribute to msg as GELF additional field, flattening groups.
+func addAttr(msg map[string]interface{}, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		if a.Key != "" {
+			prefix += a.Key + "_"
+		}
+
+		for _, ga := range a.Value.Group() {
+			addAttr(msg, prefix, ga)
+		}
+
+		return
+	}
+
+	var key = "_" + prefix + a.Key
+
+	switch a.Value.Kind() {
+	case slog.KindDuration:
+		msg[key] = a.Value.Duration().Seconds()
+	case slog.KindTime:
+		msg[key] = a.Value.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := a.Value.Any().(error); ok {
+			msg[key] = err.Error()
+		} else {
+			msg[key] = a.Value.Any()
+		}
+	default:
+		msg[key] = a.Value.Any()
+	}
+}
+
+// syslogLevel maps slog levels to GELF syslog severities.
+func syslogLevel(level slog.Level) int {
+	switch {
+	case level < slog.LevelInfo:
+		return 7 // debug
+	case level < slog.LevelWarn:
+		return 6 // informational
+	case level < slog.LevelError:
+		return 4 // warning
+	case level < slog.LevelError+4:
+		return 3 // error
+	default:
+		return 2 // critical
+	}
+}
+
+// ensure SlogHandler implements slog.Handler.
+var _ slog.Handler = (*SlogHandler)(nil)
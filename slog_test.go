@@ -0,0 +1,75 @@
+//go:build go1.21
+// +build go1.21
+
+package logger_test
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"go.cantor.systems/logger"
+)
+
+func TestNewSlogHandler(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	handler, err := logger.NewSlogHandler(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		AppName:        "test",
+		Hostname:       "localhost",
+		Transport:      logger.TransportTCP,
+		Level:          "debug",
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer handler.Close()
+
+	log := slog.New(handler).
+		With("component", "db").
+		WithGroup("request").
+		With("id", 42)
+
+	log.Warn("slow query",
+		slog.Group("query", slog.String("table", "users"), slog.Duration("took", 1500*time.Millisecond)),
+		slog.Any("err", errors.New("timeout")),
+	)
+
+	msg := <-messages
+
+	for key, expected := range map[string]interface{}{
+		"short_message":        "slow query",
+		"level":                float64(4),
+		"level_name":           "WARN",
+		"host":                 "localhost",
+		"app_name":             "test",
+		"version":              "1.1",
+		"_component":           "db",
+		"_request_id":          float64(42),
+		"_request_query_table": "users",
+		"_request_query_took":  1.5,
+		"_request_err":         "timeout",
+	} {
+		if msg[key] != expected {
+			t.Errorf("%s: expected %v but got %v", key, expected, msg[key])
+		}
+	}
+
+	if ts, ok := msg["timestamp"].(float64); !ok || time.Since(time.Unix(int64(ts), 0)) > time.Minute {
+		t.Errorf("unexpected timestamp %v", msg["timestamp"])
+	}
+}
+
+func TestSlogHandler_Enabled(t *testing.T) {
+	handler, err := logger.NewSlogHandler(logger.LoggingConfiguration{Level: "error"})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if handler.Enabled(context.Background(), slog.LevelWarn) || !handler.Enabled(context.Background(), slog.LevelError) {
+		t.Fatal("level is not applied")
+	}
+}
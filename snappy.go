@@ -0,0 +1,16 @@
+//go:build snappy
+// +build snappy
+
+package logger
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/snappy"
+)
+
+func init() {
+	newSnappyWriter = func(dst io.Writer) resetWriteCloser {
+		return snappy.NewBufferedWriter(dst)
+	}
+}
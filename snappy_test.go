@@ -0,0 +1,137 @@
+//go:build snappy
+// +build snappy
+
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/snappy"
+	"go.cantor.systems/logger/internal/testutil"
+)
+
+func TestWriterSnappy(t *testing.T) {
+	conn := &recordConn{}
+	w := &writer{
+		conn:            conn,
+		transport:       TransportUDP,
+		chunkSize:       DefaultChunkSize,
+		chunkDataSize:   DefaultChunkSize - chunkHeaderSize,
+		compressionType: CompressionSnappy,
+	}
+
+	frame := []byte(`{"version":"1.1","host":"localhost","short_message":"snappy","timestamp":1.5,"level_name":"INFO"}`)
+
+	// the second write reuses the pooled compressor
+	for i := 0; i < 2; i++ {
+		if _, err := w.Write(frame); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+	}
+
+	for _, datagram := range conn.datagrams() {
+		decoded, err := ioutil.ReadAll(snappy.NewReader(bytes.NewReader(datagram)))
+		if err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		if !bytes.Equal(decoded, frame) {
+			t.Fatalf("unexpected frame %q", decoded)
+		}
+	}
+}
+
+func BenchmarkWriterSnappy(b *testing.B) {
+	// a representative application message with a stack trace
+	frame := []byte(`{"version":"1.1","host":"api-7d9f8c-xk2lp","short_message":"request failed","full_message":"` +
+		strings.Repeat(`go.cantor.systems/api/handlers.(*Orders).Create\n\t/src/handlers/orders.go:142\n`, 20) +
+		`","timestamp":1.5,"level":3,"level_name":"ERROR","app_name":"api","pid":42,"request_id":"4b1c0f7e9a2d4e6f"}`)
+
+	for _, bench := range []struct {
+		name  string
+		kind  int
+		level int
+	}{
+		{"snappy", CompressionSnappy, 0},
+		{"gzip", CompressionGzip, gzip.BestSpeed},
+	} {
+		b.Run(bench.name, func(b *testing.B) {
+			w := &writer{
+				conn:             discardConn{},
+				transport:        TransportUDP,
+				chunkSize:        DefaultChunkSize,
+				chunkDataSize:    DefaultChunkSize - chunkHeaderSize,
+				compressionType:  bench.kind,
+				compressionLevel: bench.level,
+			}
+
+			var compressed bytes.Buffer
+			if err := w.compress(&compressed, frame); err != nil {
+				b.Fatal("error occurred:", err)
+			}
+
+			b.ReportMetric(float64(compressed.Len())/float64(len(frame)), "ratio")
+			b.ReportAllocs()
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := w.Write(frame); err != nil {
+					b.Fatal("error occurred:", err)
+				}
+			}
+		})
+	}
+}
+
+// discardConn is a net.Conn discarding the datagrams.
+type discardConn struct {
+	net.Conn
+}
+
+func (discardConn) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (discardConn) Close() error {
+	return nil
+}
+
+func TestNormalizeSnappyLevel(t *testing.T) {
+	var c = LoggingConfiguration{CompressionType: CompressionSnappy}
+	if err := c.normalize(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	c = LoggingConfiguration{CompressionType: CompressionSnappy, CompressionLevel: 1}
+	if err := c.normalize(); err == nil {
+		t.Fatal("expected error for snappy level")
+	}
+}
+
+func TestNewSnappy(t *testing.T) {
+	addr, messages, stop := testutil.NewUDPGraylog(t)
+	defer stop()
+
+	log, err := New(LoggingConfiguration{GraylogAddress: addr, CompressionType: CompressionSnappy, CompressionThreshold: -1})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	log.Info("snappy")
+
+	select {
+	case msg := <-messages:
+		if msg["short_message"] != "snappy" {
+			t.Fatalf("unexpected message: %v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("message is not received")
+	}
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"unicode/utf8"
+)
+
+// truncatedMarker ends the field shortened by truncateMessage.
+const truncatedMarker = "...[truncated]"
+
+// truncateMessage shrinks the longest string fields of the GELF JSON document buf
+// until it fits in size bytes, so the result is still a valid GELF message.
+func truncateMessage(buf []byte, size int) ([]byte, error) {
+	var msg map[string]interface{}
+
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+
+	if err := dec.Decode(&msg); err != nil {
+		return nil, fmt.Errorf("truncate message: %w", err)
+	}
+
+	var newline = bytes.HasSuffix(buf, []byte("\n"))
+
+	for len(buf) > size {
+		// only fields longer than the marker get shorter
+		var key string
+		for k, v := range msg {
+			if s, ok := v.(string); ok && len(s) > len(truncatedMarker) && (key == "" || len(s) > len(msg[key].(string))) {
+				key = k
+			}
+		}
+
+		if key == "" {
+			return nil, fmt.Errorf("truncate message: can't fit %d bytes in %d", len(buf), size)
+		}
+
+		// every cut byte shrinks the encoded document by at least one byte
+		var (
+			value  = msg[key].(string)
+			excess = len(buf) - size + len(truncatedMarker)
+			cut    = len(value) - excess
+		)
+
+		if cut < 0 {
+			cut = 0
+		}
+
+		for cut > 0 && !utf8.RuneStart(value[cut]) {
+			cut--
+		}
+
+		msg[key] = value[:cut] + truncatedMarker
+
+		var out bytes.Buffer
+
+		enc := json.NewEncoder(&out)
+		enc.SetEscapeHTML(false)
+
+		if err := enc.Encode(msg); err != nil {
+			return nil, fmt.Errorf("truncate message: %w", err)
+		}
+
+		buf = out.Bytes()
+		if !newline {
+			buf = bytes.TrimSuffix(buf, []byte("\n"))
+		}
+	}
+
+	return buf, nil
+}
@@ -0,0 +1,230 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"testing"
+)
+
+func TestWriterTruncateOversized(t *testing.T) {
+	msg, err := json.Marshal(map[string]interface{}{
+		"version":       "1.1",
+		"short_message": strings.Repeat("x", MaxChunkCount*DefaultChunkSize),
+		"level":         6,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	for _, truncate := range []bool{false, true} {
+		conn := &recordConn{}
+		w := &writer{
+			conn:              conn,
+			transport:         TransportUDP,
+			chunkSize:         DefaultChunkSize,
+			chunkDataSize:     DefaultChunkSize - chunkHeaderSize,
+			compressionType:   CompressionNone,
+			truncateOversized: truncate,
+		}
+
+		_, err = w.Write(msg)
+
+		if !truncate {
+			if !errors.Is(err, ErrTooManyChunks) {
+				t.Fatal("expected too many chunks error but got:", err)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		var gelf map[string]interface{}
+		if err = json.Unmarshal(reassemble(conn.datagrams()), &gelf); err != nil {
+			t.Fatal("truncated message is not JSON:", err)
+		}
+
+		if s, _ := gelf["short_message"].(string); !strings.HasSuffix(s, truncatedMarker) || gelf["level"] != float64(6) {
+			t.Fatalf("unexpected message %.100v", gelf)
+		}
+	}
+}
+
+func TestTruncateMessage(t *testing.T) {
+	msg := []byte(`{"a":"` + strings.Repeat("é", 20) + `","b":"short","n":1}` + "\n")
+
+	buf, err := truncateMessage(msg, len(msg)-10)
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if len(buf) > len(msg)-10 || !json.Valid(buf) || buf[len(buf)-1] != '\n' {
+		t.Fatalf("unexpected message %q", buf)
+	}
+
+	if _, err = truncateMessage([]byte(`{"n":1234567890}`), 4); err == nil {
+		t.Fatal("expected error for message without strings")
+	}
+}
+
+// recordConn is a net.Conn recording the written datagrams.
+type recordConn struct {
+	net.Conn
+
+	mu     sync.Mutex
+	writes [][]byte
+}
+
+func (c *recordConn) Write(b []byte) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.writes = append(c.writes, append([]byte(nil), b...))
+
+	return len(b), nil
+}
+
+func (c *recordConn) Close() error {
+	return nil
+}
+
+func (c *recordConn) datagrams() [][]byte {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.writes
+}
+
+// reassemble joins the datagrams of a single (possibly chunked) GELF message.
+func reassemble(datagrams [][]byte) []byte {
+	if len(datagrams) == 1 {
+		return datagrams[0]
+	}
+
+	var chunks = make([][]byte, len(datagrams))
+	for _, d := range datagrams {
+		chunks[d[10]] = d[chunkHeaderSize:]
+	}
+
+	var msg []byte
+	for _, chunk := range chunks {
+		msg = append(msg, chunk...)
+	}
+
+	return msg
+}
+
+// mtuConn is a recordConn rejecting datagrams over mtu bytes like a path with a lower MTU.
+type mtuConn struct {
+	recordConn
+
+	mtu int
+}
+
+func (c *mtuConn) Write(b []byte) (int, error) {
+	if len(b) > c.mtu {
+		return 0, &net.OpError{Op: "write", Net: "udp", Err: os.NewSyscallError("write", syscall.EMSGSIZE)}
+	}
+
+	return c.recordConn.Write(b)
+}
+
+func TestWriterMessageTooLong(t *testing.T) {
+	for _, size := range []int{1000, 5000} {
+		conn := &mtuConn{mtu: 576}
+		w := &writer{
+			conn:            conn,
+			transport:       TransportUDP,
+			chunkSize:       8192,
+			chunkDataSize:   8192 - chunkHeaderSize,
+			compressionType: CompressionNone,
+			retries:         1,
+		}
+
+		msg := []byte(strings.Repeat("x", size))
+		if _, err := w.Write(msg); err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		datagrams := conn.datagrams()
+		if expected := (size + MTUChunkSize - chunkHeaderSize - 1) / (MTUChunkSize - chunkHeaderSize); len(datagrams) != expected {
+			t.Fatalf("expected %d chunks but got %d", expected, len(datagrams))
+		}
+
+		if string(reassemble(datagrams)) != string(msg) {
+			t.Fatalf("%d bytes message is not reassembled", size)
+		}
+	}
+}
+
+func TestMessageTooLong(t *testing.T) {
+	if !messageTooLong(&net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.EMSGSIZE)}) {
+		t.Fatal("EMSGSIZE is not detected")
+	}
+
+	if messageTooLong(&net.OpError{Op: "write", Err: os.NewSyscallError("write", syscall.ECONNREFUSED)}) || messageTooLong(nil) {
+		t.Fatal("unexpected EMSGSIZE detection")
+	}
+}
+
+func TestWriterMaxMessageBytes(t *testing.T) {
+	msg, err := json.Marshal(map[string]interface{}{
+		"version":       "1.1",
+		"short_message": strings.Repeat("x", 2000),
+		"level":         6,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	for _, truncate := range []bool{false, true} {
+		var (
+			e    = &events{}
+			conn = &recordConn{}
+			w    = &writer{
+				conn:              conn,
+				transport:         TransportUDP,
+				chunkSize:         DefaultChunkSize,
+				chunkDataSize:     DefaultChunkSize - chunkHeaderSize,
+				compressionType:   CompressionNone,
+				truncateOversized: truncate,
+				maxMessageBytes:   1000,
+				onError:           e.onError,
+				onDrop:            e.onDrop,
+			}
+		)
+
+		_, err = w.Write(msg)
+
+		if !truncate {
+			if !errors.Is(err, ErrMessageTooLarge) || len(conn.datagrams()) != 0 {
+				t.Fatal("expected message too large error but got:", err)
+			}
+
+			if stats := w.Stats(); stats.Dropped != 1 || e.dropped != 1 || len(e.errors[EventDropped]) != 1 {
+				t.Fatalf("unexpected stats %+v and events %+v", stats, e.errors)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Fatal("error occurred:", err)
+		}
+
+		if len(e.errors[EventTruncated]) != 1 || !errors.Is(e.errors[EventTruncated][0], ErrMessageTooLarge) {
+			t.Fatalf("unexpected events %+v", e.errors)
+		}
+
+		if gelf := reassemble(conn.datagrams()); len(gelf) > 1000 || !strings.Contains(string(gelf), truncatedMarker) || !json.Valid(gelf) {
+			t.Fatalf("unexpected message %q", gelf)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package logger_test
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"go.cantor.systems/logger"
+	"go.cantor.systems/logger/internal/testutil"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewGELFWriter(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	w, err := logger.NewGELFWriter(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	doc := `{"version":"1.1","host":"localhost","short_message":"panic dump","level":2}`
+	if n, err := w.Write([]byte(doc)); err != nil || n != len(doc) {
+		t.Fatalf("unexpected write result %d, %v", n, err)
+	}
+
+	if msg := <-messages; msg["short_message"] != "panic dump" || msg["level"] != float64(2) {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+
+	if err = w.Close(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if _, err = w.Write([]byte(doc)); err == nil {
+		t.Fatal("expected error after close")
+	}
+}
+
+func TestNewGELFWriterError(t *testing.T) {
+	if _, err := logger.NewGELFWriter(logger.LoggingConfiguration{}); err == nil {
+		t.Fatal("expected error without address")
+	}
+
+	// nothing listens on a missing socket
+	w, err := logger.NewGELFWriter(logger.LoggingConfiguration{
+		GraylogAddress: "unix://" + filepath.Join(os.TempDir(), "missing-graylog.sock"),
+	})
+
+	var gErr *logger.GraylogError
+	if w != nil || !errors.As(err, &gErr) {
+		t.Fatalf("expected *GraylogError but got %v", err)
+	}
+}
+
+func TestNewGELFWriterTooManyChunks(t *testing.T) {
+	addr, messages, stop := testutil.NewUDPGraylog(t)
+	defer stop()
+
+	w, err := logger.NewGELFWriter(logger.LoggingConfiguration{
+		GraylogAddress:  addr,
+		CompressionType: logger.CompressionNone,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer w.Close()
+
+	if _, err = w.Write(make([]byte, logger.MaxChunkCount*logger.DefaultChunkSize)); !errors.Is(err, logger.ErrTooManyChunks) {
+		t.Fatal("expected too many chunks error but got:", err)
+	}
+
+	if _, err = w.Write([]byte(`{"short_message":"sent"}`)); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if msg := <-messages; msg["short_message"] != "sent" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+}
+
+func TestNewWriteSyncer(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	ws, err := logger.NewWriteSyncer(logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		Transport:      logger.TransportTCP,
+		Async:          true,
+		BatchSize:      1 << 20,
+		BatchInterval:  time.Hour,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zapcore.EncoderConfig{
+		MessageKey:  "short_message",
+		TimeKey:     "timestamp",
+		EncodeTime:  zapcore.EpochTimeEncoder,
+		LineEnding:  "\n",
+		LevelKey:    "level",
+		EncodeLevel: logger.SyslogLevelEncoder,
+	}), ws, zapcore.InfoLevel)
+
+	log := zap.New(core).With(zap.String("version", logger.GELFVersion), zap.String("host", "localhost"))
+	log.Warn("custom core")
+
+	// the batch is only sent by Sync
+	if err = log.Sync(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if msg := <-messages; msg["short_message"] != "custom core" || msg["level"] != float64(4) || msg["host"] != "localhost" {
+		t.Fatalf("unexpected message: %v", msg)
+	}
+
+	if err = ws.(io.Closer).Close(); err != nil {
+		t.Fatal("error occurred:", err)
+	}
+
+	if _, err = logger.NewWriteSyncer(logger.LoggingConfiguration{}); err == nil {
+		t.Fatal("expected error without address")
+	}
+}
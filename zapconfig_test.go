@@ -0,0 +1,55 @@
+package logger_test
+
+import (
+	"testing"
+
+	"go.cantor.systems/logger"
+	"go.uber.org/zap"
+)
+
+func TestNewFromZapConfig(t *testing.T) {
+	addr, messages := newTCPGraylog(t)
+
+	cfg := zap.NewDevelopmentConfig()
+	cfg.InitialFields = map[string]interface{}{"team": "payments"}
+
+	log, err := logger.NewFromZapConfig(cfg, logger.LoggingConfiguration{
+		GraylogAddress: addr,
+		AppName:        "test",
+		Transport:      logger.TransportTCP,
+	})
+	if err != nil {
+		t.Fatal("error occurred:", err)
+	}
+	defer log.Close()
+
+	// the development config enables debug
+	log.Debug("custom")
+
+	msg := <-messages
+	for key, expected := range map[string]interface{}{
+		"short_message": "custom",
+		"level":         float64(7),
+		"level_name":    "DEBUG",
+		"version":       logger.GELFVersion,
+		"app_name":      "test",
+		"team":          "payments",
+	} {
+		if msg[key] != expected {
+			t.Errorf("%s: expected %v but got %v", key, expected, msg[key])
+		}
+	}
+
+	if _, ok := msg["timestamp"].(float64); !ok {
+		t.Fatalf("GELF timestamp must be numeric but is %v", msg["timestamp"])
+	}
+}
+
+func TestNewFromZapConfigReserved(t *testing.T) {
+	cfg := zap.NewProductionConfig()
+	cfg.InitialFields = map[string]interface{}{"version": 2}
+
+	if _, err := logger.NewFromZapConfig(cfg, logger.LoggingConfiguration{}); err == nil {
+		t.Fatal("expected error for reserved initial field")
+	}
+}